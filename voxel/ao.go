@@ -0,0 +1,75 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// Voxel faces, in the order of their outward normals -X, +X, -Y, +Y, -Z, +Z.
+const (
+	FaceNegX = iota
+	FacePosX
+	FaceNegY
+	FacePosY
+	FaceNegZ
+	FacePosZ
+)
+
+func setAxis(p *Point, d, v int) {
+	switch d {
+	case 0:
+		p.X = v
+	case 1:
+		p.Y = v
+	default:
+		p.Z = v
+	}
+}
+
+// CornerOcclusion returns the ambient occlusion term of the four corners of
+// a face of the voxel at p, from 0 (fully occluded) to 1 (fully open).
+//
+// For a face whose normal lies on axis d, let u and v be the axes (d+1)%3
+// and (d+2)%3. The corners are returned in the order (-u,-v), (+u,-v),
+// (+u,+v), (-u,+v). Each corner samples the two edge neighbors and the
+// diagonal neighbor in the layer in front of the face; out-of-bounds cells
+// count as empty.
+func CornerOcclusion(img Image, p Point, face int) [4]float64 {
+	var ao [4]float64
+	b := img.Bounds()
+	d := face / 2
+	u, v := (d+1)%3, (d+2)%3
+
+	front := p.Add(faceNeighbors[face])
+	signs := [4][2]int{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+
+	for i, s := range signs {
+		var du, dv Point
+		setAxis(&du, u, s[0])
+		setAxis(&dv, v, s[1])
+
+		var side1, side2, corner int
+		if solid(img, b, front.Add(du)) {
+			side1 = 1
+		}
+		if solid(img, b, front.Add(dv)) {
+			side2 = 1
+		}
+		if solid(img, b, front.Add(du).Add(dv)) {
+			corner = 1
+		}
+
+		if side1 == 1 && side2 == 1 {
+			ao[i] = 0
+		} else {
+			ao[i] = float64(3-side1-side2-corner) / 3
+		}
+	}
+	return ao
+}
+
+// AmbientOcclusion returns the average of the corner occlusion terms of a
+// face of the voxel at p. See CornerOcclusion.
+func AmbientOcclusion(img Image, p Point, face int) float64 {
+	ao := CornerOcclusion(img, p, face)
+	return (ao[0] + ao[1] + ao[2] + ao[3]) / 4
+}
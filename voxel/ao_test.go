@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestAmbientOcclusion(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 2))
+	img.Set(1, 1, 0, 1)
+
+	if ao := AmbientOcclusion(img, Pt(1, 1, 0), FacePosZ); ao != 1 {
+		t.Errorf("open face has AO %v, expected 1", ao)
+	}
+
+	fillBox(img, Bx(0, 0, 1, 3, 3, 2), 1)
+	img.Set(1, 1, 1, 0)
+
+	if ao := AmbientOcclusion(img, Pt(1, 1, 0), FacePosZ); ao != 0 {
+		t.Errorf("occluded face has AO %v, expected 0", ao)
+	}
+
+	img.Set(2, 1, 1, 0)
+	img.Set(2, 2, 1, 0)
+	ao := CornerOcclusion(img, Pt(1, 1, 0), FacePosZ)
+	if ao != [4]float64{0, 1.0 / 3, 2.0 / 3, 0} {
+		t.Errorf("unexpected corner occlusion %v", ao)
+	}
+}
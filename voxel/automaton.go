@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// CountNeighbors returns how many of the 6 or 26 neighbors of p are
+// non-empty, as selected by connectivity. Cells outside the bounds count as
+// empty.
+func CountNeighbors(img Image, p Point, connectivity int) int {
+	return CountNeighborsEdge(img, p, connectivity, false)
+}
+
+// CountNeighborsEdge is like CountNeighbors, but cells outside the bounds
+// count as solid if solidEdge is set.
+func CountNeighborsEdge(img Image, p Point, connectivity int, solidEdge bool) int {
+	b := img.Bounds()
+	var n int
+	for _, d := range neighborhood(connectivity) {
+		q := p.Add(d)
+		if q.In(b) {
+			if img.Get(q.X, q.Y, q.Z) != 0 {
+				n++
+			}
+		} else if solidEdge {
+			n++
+		}
+	}
+	return n
+}
+
+// commonNeighbor returns the most frequent non-zero index among the 26
+// neighbors of p, preferring the lowest index on ties.
+func commonNeighbor(img Image, b Box, p Point) uint8 {
+	var counts [256]int
+	for _, d := range cornerNeighbors {
+		if q := p.Add(d); q.In(b) {
+			counts[img.Get(q.X, q.Y, q.Z)]++
+		}
+	}
+
+	best := 0
+	for i := 1; i < len(counts); i++ {
+		if counts[i] > counts[best] || best == 0 && counts[i] > 0 {
+			best = i
+		}
+	}
+	return uint8(best)
+}
+
+// Smooth runs iterations steps of a 26-connected cave automaton on a copy of
+// img. An empty cell with at least births solid neighbors becomes solid,
+// taking the most common index around it, and a solid cell with fewer than
+// survives solid neighbors becomes empty. Cells outside the bounds count as
+// empty.
+func Smooth(img Image, births, survives, iterations int) *Paletted {
+	b := img.Bounds()
+	src := NewPalettedAt(paletteOf(img), b)
+	Blit(src, img, b.Min, b)
+	dst := NewPalettedAt(src.Palette, b)
+
+	for i := 0; i < iterations; i++ {
+		for z := b.Min.Z; z < b.Max.Z; z++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					p := Point{x, y, z}
+					n := CountNeighbors(src, p, 26)
+					index := src.Get(x, y, z)
+
+					switch {
+					case index == 0 && n >= births:
+						index = commonNeighbor(src, b, p)
+					case index != 0 && n < survives:
+						index = 0
+					}
+					dst.Set(x, y, z, index)
+				}
+			}
+		}
+		src, dst = dst, src
+	}
+	return src
+}
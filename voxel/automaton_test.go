@@ -0,0 +1,70 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+func TestCountNeighbors(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 3))
+	img.Set(0, 1, 1, 1)
+	img.Set(1, 1, 2, 1)
+	img.Set(0, 0, 0, 1)
+	img.Set(2, 2, 1, 1)
+	img.Set(1, 1, 1, 1)
+
+	center := Pt(1, 1, 1)
+	if n := CountNeighbors(img, center, 6); n != 2 {
+		t.Errorf("got %d face neighbors, expected 2", n)
+	}
+	if n := CountNeighbors(img, center, 26); n != 4 {
+		t.Errorf("got %d neighbors, expected 4", n)
+	}
+
+	corner := Pt(0, 0, 0)
+	if n := CountNeighbors(img, corner, 26); n != 2 {
+		t.Errorf("got %d corner neighbors, expected 2", n)
+	}
+	if n := CountNeighborsEdge(img, corner, 26, true); n != 2+19 {
+		t.Errorf("got %d corner neighbors with solid edges, expected 21", n)
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 16, 16, 16))
+	for i := range img.Data {
+		if rnd.Intn(100) < 45 {
+			img.Data[i] = 3
+		}
+	}
+
+	a := Smooth(img, 14, 13, 4)
+	b := Smooth(img, 14, 13, 4)
+	for i := range a.Data {
+		if a.Data[i] != b.Data[i] {
+			t.Fatalf("results differ at offset %d", i)
+		}
+	}
+	if h := Histogram(img); h[3] == 0 {
+		t.Fatal("input was modified")
+	}
+
+	single := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 3))
+	single.Set(1, 1, 1, 5)
+	if h := Histogram(Smooth(single, 27, 1, 1)); h[5] != 0 {
+		t.Error("isolated voxel survived")
+	}
+
+	hole := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 3))
+	fillBox(hole, hole.Bounds(), 5)
+	hole.Set(1, 1, 1, 0)
+	if idx := Smooth(hole, 26, 0, 1).Get(1, 1, 1); idx != 5 {
+		t.Errorf("enclosed hole became %d, expected 5", idx)
+	}
+}
@@ -0,0 +1,130 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// Bitset holds one bit per cell of Bounds, in the same order as
+// Paletted.Offset. The boolean operations combine bitsets cell by cell and
+// require equal bounds.
+type Bitset struct {
+	Bounds Box
+	Bits   []uint64
+}
+
+func NewBitset(b Box) *Bitset {
+	n := b.Dx() * b.Dy() * b.Dz()
+	if b.Empty() {
+		n = 0
+	}
+	return &Bitset{b, make([]uint64, (n+63)/64)}
+}
+
+func (s *Bitset) offset(p Point) int {
+	b := s.Bounds
+	return (p.Z-b.Min.Z)*b.Dx()*b.Dy() + (p.Y-b.Min.Y)*b.Dx() + (p.X - b.Min.X)
+}
+
+// Has reports whether the cell at p is set. Cells outside the bounds are
+// never set.
+func (s *Bitset) Has(p Point) bool {
+	if !p.In(s.Bounds) {
+		return false
+	}
+	i := s.offset(p)
+	return s.Bits[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Put sets or clears the cell at p. Cells outside the bounds are ignored.
+func (s *Bitset) Put(p Point, v bool) {
+	if !p.In(s.Bounds) {
+		return
+	}
+	i := s.offset(p)
+	if v {
+		s.Bits[i/64] |= 1 << uint(i%64)
+	} else {
+		s.Bits[i/64] &^= 1 << uint(i%64)
+	}
+}
+
+// Count returns the number of set cells.
+func (s *Bitset) Count() int {
+	var n int
+	for _, w := range s.Bits {
+		for ; w != 0; w &= w - 1 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Bitset) combine(t *Bitset, op func(a, b uint64) uint64) *Bitset {
+	if s.Bounds != t.Bounds {
+		panic("voxel: bitset bounds differ")
+	}
+	r := NewBitset(s.Bounds)
+	for i := range r.Bits {
+		r.Bits[i] = op(s.Bits[i], t.Bits[i])
+	}
+	return r
+}
+
+func (s *Bitset) And(t *Bitset) *Bitset {
+	return s.combine(t, func(a, b uint64) uint64 { return a & b })
+}
+
+func (s *Bitset) Or(t *Bitset) *Bitset {
+	return s.combine(t, func(a, b uint64) uint64 { return a | b })
+}
+
+func (s *Bitset) Xor(t *Bitset) *Bitset {
+	return s.combine(t, func(a, b uint64) uint64 { return a ^ b })
+}
+
+// Not returns the complement of s within its bounds.
+func (s *Bitset) Not() *Bitset {
+	r := NewBitset(s.Bounds)
+	for i, w := range s.Bits {
+		r.Bits[i] = ^w
+	}
+	// Clear the padding past the last cell.
+	if n := s.Bounds.Dx() * s.Bounds.Dy() * s.Bounds.Dz() % 64; n != 0 {
+		r.Bits[len(r.Bits)-1] &= 1<<uint(n) - 1
+	}
+	return r
+}
+
+// Occupancy returns the cells of img holding a non-empty voxel.
+func Occupancy(img Image) *Bitset {
+	return occupancyIn(img, img.Bounds())
+}
+
+// occupancyIn is like Occupancy over the cells of b, which count as empty
+// outside the bounds of img.
+func occupancyIn(img Image, b Box) *Bitset {
+	s := NewBitset(b)
+	ib := img.Bounds()
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if solid(img, ib, Point{x, y, z}) {
+					s.Put(Point{x, y, z}, true)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// Mask returns an image covering the bounds of s with index at every set
+// cell.
+func (s *Bitset) Mask(index uint8) *Paletted {
+	img := NewPalettedAt(nil, s.Bounds)
+	for i := range img.Data {
+		if s.Bits[i/64]&(1<<uint(i%64)) != 0 {
+			img.Data[i] = index
+		}
+	}
+	return img
+}
@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestBitsetUnion(t *testing.T) {
+	r := Bx(0, 0, 0, 5, 5, 5)
+	a := NewPaletted(palette.Plan9, r)
+	b := NewPaletted(palette.Plan9, r)
+	fillBox(a, Bx(0, 0, 0, 3, 3, 3), 1)
+	fillBox(b, Bx(1, 1, 1, 4, 4, 4), 2)
+
+	sa, sb := Occupancy(a), Occupancy(b)
+	union := sa.Or(sb)
+	if n := union.Count(); n != 27+27-8 {
+		t.Errorf("union has %d cells, expected 46", n)
+	}
+	if n := sa.And(sb).Count(); n != 8 {
+		t.Errorf("intersection has %d cells, expected 8", n)
+	}
+	if n := sa.Xor(sb).Count(); n != 46-8 {
+		t.Errorf("difference has %d cells, expected 38", n)
+	}
+	if n := union.Not().Count(); n != 125-46 {
+		t.Errorf("complement has %d cells, expected 79", n)
+	}
+
+	mask := union.Mask(9)
+	if h := Histogram(mask); h[9] != 46 {
+		t.Errorf("mask has %d voxels, expected 46", h[9])
+	}
+	if mask.Get(3, 3, 3) != 9 || mask.Get(0, 0, 0) != 9 || mask.Get(4, 0, 0) != 0 {
+		t.Error("mask does not match the union")
+	}
+}
@@ -0,0 +1,52 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// combine builds an image over b holding the cells set in keep. Where a is
+// solid its index is used, so a wins ties; elsewhere the index comes from b.
+// The result uses the palette of a, and indices taken from b are not
+// remapped.
+func combine(a, b Image, r Box, keep *Bitset) *Paletted {
+	dst := NewPalettedAt(paletteOf(a), r)
+	ab := a.Bounds()
+
+	for z := r.Min.Z; z < r.Max.Z; z++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				p := Point{x, y, z}
+				if !keep.Has(p) {
+					continue
+				}
+				if solid(a, ab, p) {
+					dst.Set(x, y, z, a.Get(x, y, z))
+				} else {
+					dst.Set(x, y, z, b.Get(x, y, z))
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Union returns the voxels solid in a or b, over the union of their bounds.
+// Where both are solid, a wins.
+func Union(a, b Image) *Paletted {
+	r := a.Bounds().Union(b.Bounds())
+	return combine(a, b, r, occupancyIn(a, r).Or(occupancyIn(b, r)))
+}
+
+// Intersection returns the voxels of a that are also solid in b, over the
+// bounds of a.
+func Intersection(a, b Image) *Paletted {
+	r := a.Bounds()
+	return combine(a, b, r, occupancyIn(a, r).And(occupancyIn(b, r)))
+}
+
+// Subtract returns the voxels of a that are not solid in b, over the bounds
+// of a.
+func Subtract(a, b Image) *Paletted {
+	r := a.Bounds()
+	return combine(a, b, r, occupancyIn(a, r).And(occupancyIn(b, r).Not()))
+}
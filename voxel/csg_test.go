@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestCSG(t *testing.T) {
+	a := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	b := NewPaletted(palette.Plan9, Bx(0, 0, 0, 6, 6, 6))
+	fillBox(a, Bx(0, 0, 0, 3, 3, 3), 1)
+	fillBox(b, Bx(2, 2, 2, 5, 5, 5), 2)
+
+	u := Union(a, b)
+	if u.Bounds() != Bx(0, 0, 0, 6, 6, 6) {
+		t.Errorf("union has bounds %v", u.Bounds())
+	}
+	if h := Histogram(u); h[1] != 27 || h[2] != 26 {
+		t.Errorf("union has %d voxels of a and %d of b, expected 27 and 26", h[1], h[2])
+	}
+	if idx := u.Get(2, 2, 2); idx != 1 {
+		t.Errorf("shared voxel is %d, expected a's 1", idx)
+	}
+
+	i := Intersection(a, b)
+	if h := Histogram(i); h[1] != 1 || h[2] != 0 {
+		t.Errorf("intersection has %d voxels of a and %d of b, expected 1 and 0", h[1], h[2])
+	}
+
+	s := Subtract(a, b)
+	if h := Histogram(s); h[1] != 26 || h[2] != 0 {
+		t.Errorf("difference has %d voxels of a and %d of b, expected 26 and 0", h[1], h[2])
+	}
+	if idx := s.Get(2, 2, 2); idx != 0 {
+		t.Errorf("subtracted voxel is %d, expected 0", idx)
+	}
+}
@@ -0,0 +1,60 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// Cursor walks the voxels of a Paletted in storage order, keeping the
+// linear offset in step with the position so sequential access needs no
+// Offset computation. Like bufio.Scanner it starts before the first voxel:
+//
+//	c := img.Cursor()
+//	for c.Next() {
+//		... c.Get() ...
+//	}
+//
+// The Transformer is not applied.
+type Cursor struct {
+	p       *Paletted
+	x, y, z int
+	off     int
+}
+
+// Cursor returns a Cursor positioned before the first voxel of p.
+func (p *Paletted) Cursor() *Cursor {
+	b := p.bounds
+	return &Cursor{p: p, x: b.Min.X - 1, y: b.Min.Y, z: b.Min.Z, off: -1}
+}
+
+// Next moves to the following voxel, x varying fastest, and reports whether
+// it is within the bounds.
+func (c *Cursor) Next() bool {
+	c.off++
+	c.x++
+	if b := c.p.bounds; c.x == b.Max.X {
+		c.x = b.Min.X
+		if c.y++; c.y == b.Max.Y {
+			c.y = b.Min.Y
+			c.z++
+		}
+	}
+	return c.off < len(c.p.Data)
+}
+
+// Seek moves to the voxel at x, y, z. The following Next moves past it.
+func (c *Cursor) Seek(x, y, z int) {
+	c.x, c.y, c.z = x, y, z
+	c.off = c.p.Offset(x, y, z)
+}
+
+func (c *Cursor) Pos() Point {
+	return Point{c.x, c.y, c.z}
+}
+
+func (c *Cursor) Get() uint8 {
+	return c.p.Data[c.off]
+}
+
+func (c *Cursor) Set(index uint8) {
+	c.p.Data[c.off] = index
+}
@@ -0,0 +1,86 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	img := NewPalettedAt(palette.Plan9, Bx(-2, 1, 3, 3, 4, 5))
+	for i := range img.Data {
+		img.Data[i] = uint8(i)
+	}
+
+	var visited []Point
+	c := img.Cursor()
+	for c.Next() {
+		p := c.Pos()
+		if idx := img.Get(p.X, p.Y, p.Z); c.Get() != idx {
+			t.Fatalf("cursor at %v reads %d, expected %d", p, c.Get(), idx)
+		}
+		visited = append(visited, p)
+	}
+
+	var expected []Point
+	img.Bounds().Iterate(func(p Point) bool {
+		expected = append(expected, p)
+		return true
+	})
+	if len(visited) != len(expected) {
+		t.Fatalf("visited %d voxels, expected %d", len(visited), len(expected))
+	}
+	for i, p := range expected {
+		if visited[i] != p {
+			t.Fatalf("voxel %d is %v, expected %v", i, visited[i], p)
+		}
+	}
+
+	c.Seek(2, 3, 3)
+	c.Set(200)
+	if !c.Next() || c.Pos() != Pt(-2, 1, 4) {
+		t.Errorf("moved to %v after the end of a row", c.Pos())
+	}
+	if idx := img.Get(2, 3, 3); idx != 200 {
+		t.Errorf("got %d, expected 200", idx)
+	}
+
+	if NewPaletted(palette.Plan9, ZB).Cursor().Next() {
+		t.Error("cursor moved into an empty image")
+	}
+}
+
+func benchmarkScan(b *testing.B, cursor bool) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 128, 128, 128))
+	for i := range img.Data {
+		img.Data[i] = uint8(i)
+	}
+	r := img.Bounds()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum int
+		if cursor {
+			for c := img.Cursor(); c.Next(); {
+				sum += int(c.Get())
+			}
+		} else {
+			for z := r.Min.Z; z < r.Max.Z; z++ {
+				for y := r.Min.Y; y < r.Max.Y; y++ {
+					for x := r.Min.X; x < r.Max.X; x++ {
+						sum += int(img.Get(x, y, z))
+					}
+				}
+			}
+		}
+		if sum == 0 {
+			b.Fatal("empty sum")
+		}
+	}
+}
+
+func BenchmarkScanGet(b *testing.B)    { benchmarkScan(b, false) }
+func BenchmarkScanCursor(b *testing.B) { benchmarkScan(b, true) }
@@ -0,0 +1,124 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// VoxelChange records the index of a voxel before and after an edit.
+type VoxelChange struct {
+	Point
+	Old, New uint8
+}
+
+func getOrEmpty(img Image, b Box, x, y, z int) uint8 {
+	if (Point{x, y, z}).In(b) {
+		return img.Get(x, y, z)
+	}
+	return 0
+}
+
+// Diff returns the voxels that differ between a and b, in z, y, x order.
+// It covers the union of both bounds, treating voxels outside an image as
+// empty.
+func Diff(a, b Image) []VoxelChange {
+	var changes []VoxelChange
+	ab, bb := a.Bounds(), b.Bounds()
+	u := ab.Union(bb)
+
+	for z := u.Min.Z; z < u.Max.Z; z++ {
+		for y := u.Min.Y; y < u.Max.Y; y++ {
+			for x := u.Min.X; x < u.Max.X; x++ {
+				o, n := getOrEmpty(a, ab, x, y, z), getOrEmpty(b, bb, x, y, z)
+				if o != n {
+					changes = append(changes, VoxelChange{Point{x, y, z}, o, n})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// ApplyPatch sets every changed voxel of img to its new index. Changes
+// outside the image bounds are skipped.
+func ApplyPatch(img Image, changes []VoxelChange) {
+	b := img.Bounds()
+	for _, c := range changes {
+		if c.In(b) {
+			img.Set(c.X, c.Y, c.Z, c.New)
+		}
+	}
+}
+
+// Revert undoes ApplyPatch by setting every changed voxel of img back to its
+// old index, in reverse order. Changes outside the image bounds are skipped.
+func Revert(img Image, changes []VoxelChange) {
+	b := img.Bounds()
+	for i := len(changes) - 1; i >= 0; i-- {
+		if c := changes[i]; c.In(b) {
+			img.Set(c.X, c.Y, c.Z, c.Old)
+		}
+	}
+}
+
+// Equal reports whether a and b have the same bounds and voxel indices.
+// Palettes are not compared; see EqualPalette.
+func Equal(a, b Image) bool {
+	r := a.Bounds()
+	if !r.Eq(b.Bounds()) {
+		return false
+	}
+
+	for z := r.Min.Z; z < r.Max.Z; z++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if a.Get(x, y, z) != b.Get(x, y, z) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// EqualPalette is like Equal but also requires the palettes of a and b to
+// be equal. Images that are not Paletted have no palette.
+func EqualPalette(a, b Image) bool {
+	return Equal(a, b) && samePalette(paletteOf(a), paletteOf(b))
+}
+
+// Hash returns a 64-bit FNV-1a hash of the bounds and voxel indices of img,
+// which is stable across runs and processes. Like Equal, it ignores the
+// palette, so images that are Equal hash equally.
+func Hash(img Image) uint64 {
+	h := fnv.New64a()
+	r := img.Bounds()
+	if r.Empty() {
+		r = ZB
+	}
+
+	var buf [24]byte
+	for i, v := range []int{r.Min.X, r.Min.Y, r.Min.Z} {
+		binary.LittleEndian.PutUint64(buf[i*8:], uint64(int64(v)))
+	}
+	h.Write(buf[:])
+	for i, v := range []int{r.Max.X, r.Max.Y, r.Max.Z} {
+		binary.LittleEndian.PutUint64(buf[i*8:], uint64(int64(v)))
+	}
+	h.Write(buf[:])
+
+	row := make([]byte, r.Dx())
+	for z := r.Min.Z; z < r.Max.Z; z++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				row[x-r.Min.X] = img.Get(x, y, z)
+			}
+			h.Write(row)
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	b := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	fillBox(a, Bx(0, 0, 0, 4, 4, 2), 5)
+	fillBox(b, Bx(0, 0, 0, 4, 4, 2), 5)
+	b.Set(1, 2, 3, 7)
+
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0] != (VoxelChange{Pt(1, 2, 3), 0, 7}) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	c := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 5))
+	fillBox(c, Bx(0, 0, 0, 4, 4, 2), 5)
+	c.Set(0, 0, 4, 9)
+	changes = Diff(a, c)
+	if len(changes) != 1 || changes[0] != (VoxelChange{Pt(0, 0, 4), 0, 9}) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	a := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	b := NewPaletted(palette.Plan9, Bx(0, 0, 0, 5, 4, 4))
+	fillBox(a, Bx(0, 0, 0, 4, 4, 2), 5)
+	fillBox(b, Bx(1, 1, 1, 5, 3, 3), 8)
+
+	orig := append([]uint8(nil), a.Data...)
+	changes := Diff(a, b)
+	ApplyPatch(a, changes)
+
+	for _, c := range Diff(a, b) {
+		if c.In(a.Bounds()) {
+			t.Fatalf("voxel %v differs after patch", c.Point)
+		}
+	}
+
+	Revert(a, changes)
+	for i := range orig {
+		if a.Data[i] != orig[i] {
+			t.Fatalf("data differs at offset %d after revert", i)
+		}
+	}
+}
+
+func TestEqualHash(t *testing.T) {
+	a := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 3, 2))
+	b := NewPaletted(palette.WebSafe, Bx(0, 0, 0, 4, 3, 2))
+	fillBox(a, Bx(1, 0, 0, 3, 2, 2), 9)
+	fillBox(b, Bx(1, 0, 0, 3, 2, 2), 9)
+
+	if !Equal(a, b) || Hash(a) != Hash(b) {
+		t.Error("identical content compares or hashes differently")
+	}
+	if EqualPalette(a, b) {
+		t.Error("different palettes compare equal")
+	}
+	b.Palette = palette.Plan9
+	if !EqualPalette(a, b) {
+		t.Error("identical images compare different")
+	}
+
+	b.Set(3, 2, 1, 1)
+	if Equal(a, b) || Hash(a) == Hash(b) {
+		t.Error("different content compares or hashes equally")
+	}
+
+	c := NewPaletted(palette.Plan9, Bx(0, 0, 0, 2, 3, 4))
+	if Equal(NewPaletted(nil, Bx(0, 0, 0, 4, 3, 2)), c) || Hash(NewPaletted(nil, Bx(0, 0, 0, 4, 3, 2))) == Hash(c) {
+		t.Error("images with different bounds compare or hash equally")
+	}
+}
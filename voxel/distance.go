@@ -0,0 +1,99 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "math"
+
+type chamferStep struct {
+	d Point
+	w float32
+}
+
+var forwardChamfer, backwardChamfer = func() (fw, bw []chamferStep) {
+	for _, d := range cornerNeighbors {
+		w := float32(math.Sqrt(float64(d.X*d.X + d.Y*d.Y + d.Z*d.Z)))
+		if d.Z < 0 || d.Z == 0 && (d.Y < 0 || d.Y == 0 && d.X < 0) {
+			fw = append(fw, chamferStep{d, w})
+		} else {
+			bw = append(bw, chamferStep{d, w})
+		}
+	}
+	return
+}()
+
+// chamfer computes, for every cell, the approximate distance to the nearest
+// seed cell. Cells outside b are treated as being at distance outside.
+func chamfer(b Box, seed func(p Point) bool, outside float32) []float32 {
+	w, h := b.Dx(), b.Dy()
+	inf := float32(math.Inf(1))
+	dist := make([]float32, w*h*b.Dz())
+
+	offset := func(p Point) int {
+		p = p.Sub(b.Min)
+		return p.Z*w*h + p.Y*w + p.X
+	}
+
+	relax := func(p Point, steps []chamferStep) {
+		o := offset(p)
+		d := dist[o]
+		for _, s := range steps {
+			v := outside
+			if q := p.Add(s.d); q.In(b) {
+				v = dist[offset(q)]
+			}
+			if v += s.w; v < d {
+				d = v
+			}
+		}
+		dist[o] = d
+	}
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				if seed(p) {
+					dist[offset(p)] = 0
+				} else {
+					dist[offset(p)] = inf
+					relax(p, forwardChamfer)
+				}
+			}
+		}
+	}
+
+	for z := b.Max.Z - 1; z >= b.Min.Z; z-- {
+		for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+			for x := b.Max.X - 1; x >= b.Min.X; x-- {
+				relax(Point{x, y, z}, backwardChamfer)
+			}
+		}
+	}
+	return dist
+}
+
+// DistanceField returns the approximate signed distance from each cell to
+// the surface of the non-empty voxels, computed with a two-pass chamfer
+// transform. Distances are negative inside and positive outside, with the
+// surface lying on the voxel faces, and are laid out like Paletted.Offset
+// relative to the image bounds. Cells outside the bounds count as empty.
+func DistanceField(img Image) []float32 {
+	b := img.Bounds()
+	isSolid := func(p Point) bool {
+		return img.Get(p.X, p.Y, p.Z) != 0
+	}
+
+	outer := chamfer(b, isSolid, float32(math.Inf(1)))
+	inner := chamfer(b, func(p Point) bool { return !isSolid(p) }, 0)
+
+	for i, d := range outer {
+		if d == 0 {
+			outer[i] = 0.5 - inner[i]
+		} else {
+			outer[i] = d - 0.5
+		}
+	}
+	return outer
+}
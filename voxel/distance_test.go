@@ -0,0 +1,43 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestDistanceField(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 15, 15, 15))
+	center := Pt(7, 7, 7)
+
+	for z := 0; z < 15; z++ {
+		for y := 0; y < 15; y++ {
+			for x := 0; x < 15; x++ {
+				if d := Pt(x, y, z).Sub(center); d.X*d.X+d.Y*d.Y+d.Z*d.Z <= 25 {
+					img.Set(x, y, z, 1)
+				}
+			}
+		}
+	}
+
+	field := DistanceField(img)
+	deepest := 0
+	for i, d := range field {
+		if d < field[deepest] {
+			deepest = i
+		}
+		if inside := img.Data[i] != 0; inside != (d < 0) {
+			t.Fatalf("cell %d has distance %v but inside is %v", i, d, inside)
+		}
+	}
+
+	if deepest != img.Offset(7, 7, 7) {
+		t.Errorf("largest negative distance %v at offset %d, expected the center", field[deepest], deepest)
+	}
+	if d := field[img.Offset(0, 7, 7)]; d < 1 {
+		t.Errorf("outside distance %v, expected at least 1", d)
+	}
+}
@@ -0,0 +1,176 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+func copyOp(dst, src Image, dx, dy, dz, sx, sy, sz int) {
+	dst.Set(dx, dy, dz, src.Get(sx, sy, sz))
+}
+
+// Draw aligns r.Min in dst with sp in src and applies op to every voxel of r
+// that lies within both images. A nil op copies the source voxels.
+func Draw(dst Image, r Box, src Image, sp Point, op Op) {
+	if op == nil {
+		op = copyOp
+	}
+
+	orig := r.Min
+	r = r.Intersect(dst.Bounds())
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	if r.Empty() {
+		return
+	}
+
+	sp = sp.Add(r.Min.Sub(orig))
+	BlitOp(dst, src, r.Min, Box{sp, sp.Add(r.Size())}, op)
+}
+
+func inSphere(p, center Point, radius int) bool {
+	d := p.Sub(center)
+	return d.X*d.X+d.Y*d.Y+d.Z*d.Z <= radius*radius
+}
+
+func drawSphere(img Image, center Point, radius int, index uint8, shell bool) {
+	b := Box{center.Sub(Pt(radius, radius, radius)), center.Add(Pt(radius+1, radius+1, radius+1))}
+	b = b.Intersect(img.Bounds())
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				if !inSphere(p, center, radius) {
+					continue
+				}
+
+				if shell {
+					inside := true
+					for _, d := range faceNeighbors {
+						inside = inside && inSphere(p.Add(d), center, radius)
+					}
+					if inside {
+						continue
+					}
+				}
+				img.Set(x, y, z, index)
+			}
+		}
+	}
+}
+
+// DrawSphere sets every voxel within radius of center to index.
+func DrawSphere(img Image, center Point, radius int, index uint8) {
+	drawSphere(img, center, radius, index, false)
+}
+
+// DrawSphereShell sets the voxels of the sphere drawn by DrawSphere that
+// have a face neighbor outside of it.
+func DrawSphereShell(img Image, center Point, radius int, index uint8) {
+	drawSphere(img, center, radius, index, true)
+}
+
+// DrawBox sets the voxels of b to index. If filled is false only the six
+// faces of b are drawn. The faces are those of b, not of b clipped to the
+// image bounds.
+func DrawBox(img Image, b Box, index uint8, filled bool) {
+	c := b.Intersect(img.Bounds())
+
+	for z := c.Min.Z; z < c.Max.Z; z++ {
+		for y := c.Min.Y; y < c.Max.Y; y++ {
+			for x := c.Min.X; x < c.Max.X; x++ {
+				if filled ||
+					x == b.Min.X || x == b.Max.X-1 ||
+					y == b.Min.Y || y == b.Max.Y-1 ||
+					z == b.Min.Z || z == b.Max.Z-1 {
+					img.Set(x, y, z, index)
+				}
+			}
+		}
+	}
+}
+
+// DrawLine sets the cells of Line(p0, p1) that lie within the bounds to
+// index.
+func DrawLine(img Image, p0, p1 Point, index uint8) {
+	b := img.Bounds()
+	for _, p := range Line(p0, p1) {
+		if p.In(b) {
+			img.Set(p.X, p.Y, p.Z, index)
+		}
+	}
+}
+
+// MirrorStamp copies every non-empty voxel of img to its mirror image across
+// the plane where axis d (0 for X, 1 for Y, 2 for Z) equals plane, as in
+// Box.MirrorX. Empty voxels never overwrite, so stamping a half model
+// completes it. Voxels are read before any are written, and mirror positions
+// outside the bounds are skipped.
+func MirrorStamp(img Image, d, plane int) {
+	type stamp struct {
+		p     Point
+		index uint8
+	}
+
+	b := img.Bounds()
+	var stamps []stamp
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if index := img.Get(x, y, z); index != 0 {
+					p := Point{x, y, z}
+					setAxis(&p, d, 2*plane-1-coord(p, d))
+					if p.In(b) {
+						stamps = append(stamps, stamp{p, index})
+					}
+				}
+			}
+		}
+	}
+
+	for _, s := range stamps {
+		img.Set(s.p.X, s.p.Y, s.p.Z, s.index)
+	}
+}
+
+// FillNoise sets every voxel of img to fn(x, y, z), visiting them in z, y, x
+// order with x varying fastest.
+func FillNoise(img Image, fn func(x, y, z int) uint8) {
+	b := img.Bounds()
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				img.Set(x, y, z, fn(x, y, z))
+			}
+		}
+	}
+}
+
+// SymmetryPlanes reports, for each axis, whether every voxel of img has the
+// same index as the voxel at Min+Max-1-c on that axis, where c is its own
+// coordinate. That reverses the bounds along the axis, so for an odd extent
+// the plane runs through the middle voxel layer. Voxel indices are compared,
+// not just occupancy. img is not modified.
+func SymmetryPlanes(img Image) (x, y, z bool) {
+	b := img.Bounds()
+	var sym [3]bool
+	for d := range sym {
+		sym[d] = symmetric(img, b, d)
+	}
+	return sym[0], sym[1], sym[2]
+}
+
+func symmetric(img Image, b Box, d int) bool {
+	mirror := coord(b.Min, d) + coord(b.Max, d) - 1
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				setAxis(&p, d, mirror-coord(p, d))
+				if img.Get(x, y, z) != img.Get(p.X, p.Y, p.Z) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"math"
+	"testing"
+)
+
+func TestDraw(t *testing.T) {
+	src := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	for i := range src.Data {
+		src.Data[i] = uint8(i + 1)
+	}
+
+	dst := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+
+	// Fully clipped by the destination.
+	Draw(dst, Bx(5, 5, 5, 8, 8, 8), src, ZP, nil)
+	// Fully clipped by the source.
+	Draw(dst, Bx(0, 0, 0, 4, 4, 4), src, Pt(4, 0, 0), nil)
+	if h := Histogram(dst); h[0] != 64 {
+		t.Fatalf("clipped draws modified %d voxels", 64-h[0])
+	}
+
+	// Partially clipped by both images.
+	Draw(dst, Bx(-1, 2, 2, 3, 6, 6), src, Pt(0, 1, 0), nil)
+	for z := 0; z < 4; z++ {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				var expected uint8
+				if x < 3 && y >= 2 && z >= 2 {
+					expected = src.Get(x+1, y-1, z-2)
+				}
+				if idx := dst.Get(x, y, z); idx != expected {
+					t.Errorf("voxel %v is %d, expected %d", Pt(x, y, z), idx, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestDrawSphere(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 32, 32, 32))
+	DrawSphere(img, Pt(16, 16, 16), 10, 1)
+
+	volume := 4.0 / 3.0 * math.Pi * 1000
+	if n := float64(Histogram(img)[1]); math.Abs(n-volume) > volume*0.02 {
+		t.Errorf("got %v voxels, expected about %v", n, volume)
+	}
+
+	// Clipped to the bounds.
+	img = NewPaletted(palette.Plan9, Bx(0, 0, 0, 32, 32, 32))
+	DrawSphere(img, Pt(0, 16, 16), 10, 1)
+	if n := float64(Histogram(img)[1]); math.Abs(n-volume/2) > volume*0.05 {
+		t.Errorf("got %v voxels, expected about %v", n, volume/2)
+	}
+}
+
+func TestDrawSphereShell(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 32, 32, 32))
+	DrawSphereShell(img, Pt(16, 16, 16), 10, 1)
+
+	if idx := img.Get(16, 16, 16); idx != 0 {
+		t.Error("shell has a filled center")
+	}
+	if idx := img.Get(26, 16, 16); idx != 1 {
+		t.Error("shell is missing its surface")
+	}
+
+	// Filling the shell from the center must not leak out of it.
+	FloodFill(img, Pt(16, 16, 16), 2)
+	if idx := img.Get(0, 0, 0); idx != 0 {
+		t.Error("shell is not closed")
+	}
+}
+
+func TestDrawBox(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 6, 6, 6))
+	DrawBox(img, Bx(1, 1, 1, 5, 5, 5), 3, true)
+	if h := Histogram(img); h[3] != 64 {
+		t.Errorf("filled box has %d voxels, expected 64", h[3])
+	}
+
+	img = NewPaletted(palette.Plan9, Bx(0, 0, 0, 6, 6, 6))
+	DrawBox(img, Bx(1, 1, 1, 5, 5, 5), 3, false)
+	if h := Histogram(img); h[3] != 64-8 {
+		t.Errorf("outlined box has %d voxels, expected 56", h[3])
+	}
+	if b := Trim(Erode(img)); !b.Empty() {
+		t.Errorf("outlined box has interior voxels in %v", b)
+	}
+	if b := Trim(img); b != Bx(1, 1, 1, 5, 5, 5) {
+		t.Errorf("outlined box spans %v", b)
+	}
+
+	// Clipped faces are not drawn.
+	img = NewPaletted(palette.Plan9, Bx(0, 0, 0, 6, 6, 6))
+	DrawBox(img, Bx(-2, 0, 0, 3, 3, 3), 3, false)
+	if idx := img.Get(0, 1, 1); idx != 0 {
+		t.Errorf("interior voxel is %d, expected 0", idx)
+	}
+}
+
+func TestDrawLine(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 8, 8, 8))
+	DrawLine(img, Pt(-4, 2, 3), Pt(11, 2, 3), 5)
+
+	if h := Histogram(img); h[5] != 8 {
+		t.Errorf("got %d voxels, expected 8", h[5])
+	}
+	for x := 0; x < 8; x++ {
+		if idx := img.Get(x, 2, 3); idx != 5 {
+			t.Errorf("voxel %v is %d, expected 5", Pt(x, 2, 3), idx)
+		}
+	}
+}
+
+func TestMirrorStamp(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 8, 4, 4))
+	img.Set(1, 2, 3, 7)
+	img.Set(6, 0, 0, 9)
+
+	MirrorStamp(img, 0, 4)
+	if idx := img.Get(6, 2, 3); idx != 7 {
+		t.Errorf("mirror twin is %d, expected 7", idx)
+	}
+	if idx := img.Get(1, 2, 3); idx != 7 {
+		t.Errorf("original is %d, expected 7", idx)
+	}
+	if idx := img.Get(1, 0, 0); idx != 9 {
+		t.Errorf("mirror twin is %d, expected 9", idx)
+	}
+	if h := Histogram(img); h[0] != 128-4 {
+		t.Errorf("got %d voxels, expected 4", 128-h[0])
+	}
+}
+
+func TestFillNoise(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	checker := func(x, y, z int) uint8 {
+		return uint8((x+y+z)%2 + 1)
+	}
+	FillNoise(img, checker)
+
+	for z := 0; z < 4; z++ {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				if idx := img.Get(x, y, z); idx != checker(x, y, z) {
+					t.Fatalf("voxel %v is %d, expected %d", Pt(x, y, z), idx, checker(x, y, z))
+				}
+			}
+		}
+	}
+
+	last := -1
+	FillNoise(img, func(x, y, z int) uint8 {
+		if o := img.Offset(x, y, z); o <= last {
+			t.Fatalf("visited %v out of order", Pt(x, y, z))
+		} else {
+			last = o
+		}
+		return 0
+	})
+}
+
+func TestSymmetryPlanes(t *testing.T) {
+	img := NewPalettedAt(palette.Plan9, Bx(-3, 0, 2, 3, 5, 6))
+	DrawBox(img, Bx(-2, 1, 3, 2, 4, 5), 4, true)
+	img.Set(-3, 2, 2, 7)
+	img.Set(2, 2, 2, 7)
+
+	if x, y, z := SymmetryPlanes(img); !x || !y || z {
+		t.Errorf("got symmetry %v %v %v, expected true true false", x, y, z)
+	}
+
+	// Same occupancy, different index.
+	img.Set(2, 2, 2, 8)
+	if x, _, _ := SymmetryPlanes(img); x {
+		t.Error("differently colored mirror voxels counted as symmetric")
+	}
+}
@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+var faceNeighbors = [6]Point{
+	{-1, 0, 0}, {1, 0, 0},
+	{0, -1, 0}, {0, 1, 0},
+	{0, 0, -1}, {0, 0, 1},
+}
+
+// FloodFill replaces the 6-connected region of voxels sharing the index at
+// start with newIndex. The region never extends past the image bounds.
+func FloodFill(img Image, start Point, newIndex uint8) {
+	b := img.Bounds()
+	if !start.In(b) {
+		return
+	}
+
+	oldIndex := img.Get(start.X, start.Y, start.Z)
+	if oldIndex == newIndex {
+		return
+	}
+
+	img.Set(start.X, start.Y, start.Z, newIndex)
+	stack := []Point{start}
+
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, d := range faceNeighbors {
+			q := p.Add(d)
+			if q.In(b) && img.Get(q.X, q.Y, q.Z) == oldIndex {
+				img.Set(q.X, q.Y, q.Z, newIndex)
+				stack = append(stack, q)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func fillBox(img Image, b Box, index uint8) {
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				img.Set(x, y, z, index)
+			}
+		}
+	}
+}
+
+func TestFloodFill(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 9, 5, 5))
+
+	// Two chambers separated by a wall at x == 4.
+	for z := 0; z < 5; z++ {
+		for y := 0; y < 5; y++ {
+			img.Set(4, y, z, 1)
+		}
+	}
+
+	FloodFill(img, Pt(1, 1, 1), 2)
+
+	for z := 0; z < 5; z++ {
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 9; x++ {
+				var expected uint8
+				switch {
+				case x < 4:
+					expected = 2
+				case x == 4:
+					expected = 1
+				}
+				if idx := img.Get(x, y, z); idx != expected {
+					t.Fatalf("voxel %v is %d, expected %d", Pt(x, y, z), idx, expected)
+				}
+			}
+		}
+	}
+}
@@ -5,8 +5,10 @@
 package voxel
 
 import (
+	"encoding/json"
 	"fmt"
 	"image/color"
+	"math"
 )
 
 type Point struct {
@@ -17,6 +19,23 @@ func (p Point) String() string {
 	return fmt.Sprintf("(%d,%d,%d)", p.X, p.Y, p.Z)
 }
 
+// MarshalJSON encodes p as the array [x,y,z].
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([3]int{p.X, p.Y, p.Z})
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var v []int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v) != 3 {
+		return fmt.Errorf("voxel: point has %d coordinates, expected 3", len(v))
+	}
+	*p = Point{v[0], v[1], v[2]}
+	return nil
+}
+
 func (p Point) Add(q Point) Point {
 	return Point{p.X + q.X, p.Y + q.Y, p.Z + q.Z}
 }
@@ -33,6 +52,34 @@ func (p Point) Div(k int) Point {
 	return Point{p.X / k, p.Y / k, p.Z / k}
 }
 
+// Min returns the componentwise minimum of p and q.
+func (p Point) Min(q Point) Point {
+	if q.X < p.X {
+		p.X = q.X
+	}
+	if q.Y < p.Y {
+		p.Y = q.Y
+	}
+	if q.Z < p.Z {
+		p.Z = q.Z
+	}
+	return p
+}
+
+// Max returns the componentwise maximum of p and q.
+func (p Point) Max(q Point) Point {
+	if q.X > p.X {
+		p.X = q.X
+	}
+	if q.Y > p.Y {
+		p.Y = q.Y
+	}
+	if q.Z > p.Z {
+		p.Z = q.Z
+	}
+	return p
+}
+
 func (p Point) In(b Box) bool {
 	return b.Min.X <= p.X && p.X < b.Max.X &&
 		b.Min.Y <= p.Y && p.Y < b.Max.Y &&
@@ -67,6 +114,38 @@ func Pt(X, Y, Z int) Point {
 	return Point{X, Y, Z}
 }
 
+type Pointf struct {
+	X, Y, Z float64
+}
+
+func (p Pointf) String() string {
+	return fmt.Sprintf("(%g,%g,%g)", p.X, p.Y, p.Z)
+}
+
+func (p Pointf) Add(q Pointf) Pointf {
+	return Pointf{p.X + q.X, p.Y + q.Y, p.Z + q.Z}
+}
+
+func (p Pointf) Sub(q Pointf) Pointf {
+	return Pointf{p.X - q.X, p.Y - q.Y, p.Z - q.Z}
+}
+
+func (p Pointf) Mul(k float64) Pointf {
+	return Pointf{p.X * k, p.Y * k, p.Z * k}
+}
+
+func (p Pointf) Len() float64 {
+	return math.Sqrt(p.X*p.X + p.Y*p.Y + p.Z*p.Z)
+}
+
+func (p Pointf) Floor() Point {
+	return Point{int(math.Floor(p.X)), int(math.Floor(p.Y)), int(math.Floor(p.Z))}
+}
+
+func Ptf(X, Y, Z float64) Pointf {
+	return Pointf{X, Y, Z}
+}
+
 type Box struct {
 	Min, Max Point
 }
@@ -75,6 +154,25 @@ func (b Box) String() string {
 	return b.Min.String() + "-" + b.Max.String()
 }
 
+type jsonBox struct {
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+// MarshalJSON encodes b as {"min":[x,y,z],"max":[x,y,z]}.
+func (b Box) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBox(b))
+}
+
+func (b *Box) UnmarshalJSON(data []byte) error {
+	var v jsonBox
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = Box(v)
+	return nil
+}
+
 func (b Box) Dx() int {
 	return b.Max.X - b.Min.X
 }
@@ -134,6 +232,35 @@ func (b Box) Inset(n int) Box {
 	return b
 }
 
+// ShrinkClamp moves every side of b n cells inwards. Unlike Inset, which
+// collapses a too small box onto its center, ShrinkClamp returns ZB when the
+// result would be empty.
+func (b Box) ShrinkClamp(n int) Box {
+	b = b.Expand(Point{-n, -n, -n})
+	if b.Empty() {
+		return ZB
+	}
+	return b
+}
+
+// Expand grows b by p on every side, per axis. Negative components shrink
+// b instead; shrinking past the center leaves an inverted box, which is
+// Empty.
+func (b Box) Expand(p Point) Box {
+	return Box{b.Min.Sub(p), b.Max.Add(p)}
+}
+
+// Scale multiplies the extents of b by k, keeping Min fixed.
+func (b Box) Scale(k int) Box {
+	return Box{b.Min, b.Min.Add(b.Size().Mul(k))}.Canon()
+}
+
+// ScaleAbout multiplies the distances of the corners of b from c by k. Use
+// the center of b as c to scale it in place.
+func (b Box) ScaleAbout(k int, c Point) Box {
+	return Box{b.Min.Sub(c).Mul(k).Add(c), b.Max.Sub(c).Mul(k).Add(c)}.Canon()
+}
+
 func (b Box) Intersect(s Box) Box {
 	if b.Min.X < s.Min.X {
 		b.Min.X = s.Min.X
@@ -159,6 +286,33 @@ func (b Box) Intersect(s Box) Box {
 	return b
 }
 
+// IntersectOK is like Intersect but also reports whether the intersection
+// is non-empty.
+func (b Box) IntersectOK(s Box) (Box, bool) {
+	b = b.Intersect(s)
+	return b, !b.Empty()
+}
+
+// OverlapVolume returns the number of cells in b.Intersect(s), without
+// building the intersection.
+func (b Box) OverlapVolume(s Box) int {
+	overlap := func(b0, b1, s0, s1 int) int {
+		if s0 > b0 {
+			b0 = s0
+		}
+		if s1 < b1 {
+			b1 = s1
+		}
+		if b1 < b0 {
+			return 0
+		}
+		return b1 - b0
+	}
+	return overlap(b.Min.X, b.Max.X, s.Min.X, s.Max.X) *
+		overlap(b.Min.Y, b.Max.Y, s.Min.Y, s.Max.Y) *
+		overlap(b.Min.Z, b.Max.Z, s.Min.Z, s.Max.Z)
+}
+
 func (b Box) Union(s Box) Box {
 	if b.Empty() {
 		return s
@@ -211,6 +365,94 @@ func (b Box) In(s Box) bool {
 		s.Min.Z <= b.Min.Z && b.Max.Z <= s.Max.Z
 }
 
+// ClosestPoint returns the cell of b nearest to p. The result is undefined
+// for an empty box.
+func (b Box) ClosestPoint(p Point) Point {
+	clamp := func(v, lo, hi int) int {
+		if v < lo {
+			return lo
+		}
+		if v >= hi {
+			return hi - 1
+		}
+		return v
+	}
+	return Point{
+		clamp(p.X, b.Min.X, b.Max.X),
+		clamp(p.Y, b.Min.Y, b.Max.Y),
+		clamp(p.Z, b.Min.Z, b.Max.Z),
+	}
+}
+
+// DistanceTo returns the Manhattan distance from p to the nearest cell of
+// b, which is 0 if p is in b.
+func (b Box) DistanceTo(p Point) int {
+	d := p.Sub(b.ClosestPoint(p))
+	if d.X < 0 {
+		d.X = -d.X
+	}
+	if d.Y < 0 {
+		d.Y = -d.Y
+	}
+	if d.Z < 0 {
+		d.Z = -d.Z
+	}
+	return d.X + d.Y + d.Z
+}
+
+// Iterate calls fn for every cell of b in z, y, x order, stopping as soon as
+// fn returns false.
+func (b Box) Iterate(fn func(p Point) bool) {
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if !fn(Point{x, y, z}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Tiles splits b into boxes of the given size, starting at b.Min and in z,
+// y, x order. Tiles along the far sides are clipped to b. It returns nil if
+// any component of size is less than one.
+func (b Box) Tiles(size Point) []Box {
+	if size.X < 1 || size.Y < 1 || size.Z < 1 {
+		return nil
+	}
+
+	var tiles []Box
+	for z := b.Min.Z; z < b.Max.Z; z += size.Z {
+		for y := b.Min.Y; y < b.Max.Y; y += size.Y {
+			for x := b.Min.X; x < b.Max.X; x += size.X {
+				t := Box{Point{x, y, z}, Point{x, y, z}.Add(size)}
+				tiles = append(tiles, t.Intersect(b))
+			}
+		}
+	}
+	return tiles
+}
+
+// MirrorX reflects b across the plane x = plane. The plane lies on a cell
+// boundary, so cell x maps to cell 2*plane-1-x.
+func (b Box) MirrorX(plane int) Box {
+	b.Min.X, b.Max.X = 2*plane-b.Max.X, 2*plane-b.Min.X
+	return b.Canon()
+}
+
+// MirrorY is like MirrorX, across the plane y = plane.
+func (b Box) MirrorY(plane int) Box {
+	b.Min.Y, b.Max.Y = 2*plane-b.Max.Y, 2*plane-b.Min.Y
+	return b.Canon()
+}
+
+// MirrorZ is like MirrorX, across the plane z = plane.
+func (b Box) MirrorZ(plane int) Box {
+	b.Min.Z, b.Max.Z = 2*plane-b.Max.Z, 2*plane-b.Min.Z
+	return b.Canon()
+}
+
 func (b Box) Canon() Box {
 	if b.Max.X < b.Min.X {
 		b.Min.X, b.Max.X = b.Max.X, b.Min.X
@@ -253,3 +495,81 @@ func Bx(x0, y0, z0, x1, y1, z1 int) Box {
 	}
 	return Box{Point{x0, y0, z0}, Point{x1, y1, z1}}
 }
+
+// GrowToInclude returns the smallest box containing b and the cell at p.
+// Growing ZB gives the box of that cell alone.
+func (b Box) GrowToInclude(p Point) Box {
+	return b.Union(Box{p, p.Add(Point{1, 1, 1})})
+}
+
+// BoundingBox returns the smallest box containing all points, or ZB if there
+// are none.
+func BoundingBox(points ...Point) Box {
+	if len(points) == 0 {
+		return ZB
+	}
+
+	b := Box{points[0], points[0]}
+	for _, p := range points[1:] {
+		b.Min = b.Min.Min(p)
+		b.Max = b.Max.Max(p)
+	}
+	b.Max = b.Max.Add(Point{1, 1, 1})
+	return b
+}
+
+// Line returns the cells of the 3D Bresenham line from p0 to p1, both
+// included.
+func Line(p0, p1 Point) []Point {
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	sign := func(v int) int {
+		switch {
+		case v < 0:
+			return -1
+		case v > 0:
+			return 1
+		}
+		return 0
+	}
+
+	d := p1.Sub(p0)
+	ax, ay, az := abs(d.X), abs(d.Y), abs(d.Z)
+	s := Point{sign(d.X), sign(d.Y), sign(d.Z)}
+
+	n := ax
+	if ay > n {
+		n = ay
+	}
+	if az > n {
+		n = az
+	}
+
+	points := make([]Point, 0, n+1)
+	p := p0
+	ex, ey, ez := n/2, n/2, n/2
+
+	for i := 0; i <= n; i++ {
+		points = append(points, p)
+		ex -= ax
+		if ex < 0 {
+			ex += n
+			p.X += s.X
+		}
+		ey -= ay
+		if ey < 0 {
+			ey += n
+			p.Y += s.Y
+		}
+		ez -= az
+		if ez < 0 {
+			ez += n
+			p.Z += s.Z
+		}
+	}
+	return points
+}
@@ -0,0 +1,276 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	points := Line(Pt(0, 0, 0), Pt(6, -3, 2))
+	if len(points) != 7 {
+		t.Fatalf("got %d points, expected 7", len(points))
+	}
+	if points[0] != Pt(0, 0, 0) || points[6] != Pt(6, -3, 2) {
+		t.Errorf("line runs from %v to %v", points[0], points[6])
+	}
+
+	for i := 1; i < len(points); i++ {
+		d := points[i].Sub(points[i-1])
+		if d.X != 1 || d.Y < -1 || d.Y > 0 || d.Z < 0 || d.Z > 1 {
+			t.Errorf("step %v from %v is not a unit step", d, points[i-1])
+		}
+	}
+
+	if points := Line(Pt(1, 2, 3), Pt(1, 2, 3)); len(points) != 1 {
+		t.Errorf("got %d points for a single cell", len(points))
+	}
+}
+
+func TestBoxExpand(t *testing.T) {
+	b := Bx(0, 0, 0, 4, 4, 4)
+	if e := b.Expand(Pt(1, 2, 0)); e != Bx(-1, -2, 0, 5, 6, 4) {
+		t.Errorf("expanded to %v", e)
+	}
+	if e := b.Expand(Pt(-1, 0, 3)); e != Bx(1, 0, -3, 3, 4, 7) {
+		t.Errorf("expanded to %v", e)
+	}
+	if e := b.Expand(Pt(-3, 0, 0)); !e.Empty() {
+		t.Errorf("expanded to %v, expected an empty box", e)
+	}
+}
+
+func TestBoxScale(t *testing.T) {
+	b := Bx(1, 1, 1, 2, 2, 2)
+	if s := b.Scale(2); s != Bx(1, 1, 1, 3, 3, 3) {
+		t.Errorf("scaled to %v", s)
+	}
+	if s := Bx(0, 0, 0, 2, 4, 6).ScaleAbout(2, Pt(1, 2, 3)); s != Bx(-1, -2, -3, 3, 6, 9) {
+		t.Errorf("scaled to %v", s)
+	}
+	if s := b.ScaleAbout(2, ZP); s != Bx(2, 2, 2, 4, 4, 4) {
+		t.Errorf("scaled to %v", s)
+	}
+}
+
+func TestBoxClosestPoint(t *testing.T) {
+	b := Bx(0, 0, 0, 4, 4, 4)
+	tests := []struct {
+		p, closest Point
+		dist       int
+	}{
+		{Pt(1, 2, 3), Pt(1, 2, 3), 0},
+		{Pt(3, 0, 2), Pt(3, 0, 2), 0},
+		{Pt(4, 2, 2), Pt(3, 2, 2), 1},
+		{Pt(-5, 10, 2), Pt(0, 3, 2), 12},
+	}
+
+	for _, tt := range tests {
+		if c := b.ClosestPoint(tt.p); c != tt.closest {
+			t.Errorf("closest point to %v is %v, expected %v", tt.p, c, tt.closest)
+		}
+		if d := b.DistanceTo(tt.p); d != tt.dist {
+			t.Errorf("distance to %v is %d, expected %d", tt.p, d, tt.dist)
+		}
+	}
+}
+
+func TestBoxIterate(t *testing.T) {
+	b := Bx(0, 0, 0, 3, 3, 3)
+	target := Pt(1, 0, 1)
+
+	var n int
+	var found Point
+	b.Iterate(func(p Point) bool {
+		n++
+		if p == target {
+			found = p
+			return false
+		}
+		return true
+	})
+	if found != target || n != 11 {
+		t.Errorf("stopped at %v after %d cells, expected %v after 11", found, n, target)
+	}
+
+	n = 0
+	b.Iterate(func(p Point) bool { n++; return true })
+	if n != 27 {
+		t.Errorf("visited %d cells, expected 27", n)
+	}
+
+	ZB.Iterate(func(p Point) bool {
+		t.Fatal("visited a cell of an empty box")
+		return true
+	})
+}
+
+func TestPointMinMax(t *testing.T) {
+	p, q := Pt(-3, 5, 0), Pt(2, -7, 0)
+	if m := p.Min(q); m != Pt(-3, -7, 0) {
+		t.Errorf("min is %v", m)
+	}
+	if m := p.Max(q); m != Pt(2, 5, 0) {
+		t.Errorf("max is %v", m)
+	}
+	if m := q.Min(p); m != p.Min(q) {
+		t.Errorf("min is not symmetric: %v", m)
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	b := BoundingBox(Pt(1, -2, 3), Pt(-1, 4, 3), Pt(0, 0, 5))
+	if b != Bx(-1, -2, 3, 2, 5, 6) {
+		t.Errorf("got %v", b)
+	}
+	for _, p := range []Point{Pt(1, -2, 3), Pt(-1, 4, 3), Pt(0, 0, 5)} {
+		if !p.In(b) {
+			t.Errorf("%v is not in %v", p, b)
+		}
+	}
+	if b := BoundingBox(); b != ZB {
+		t.Errorf("got %v for no points", b)
+	}
+}
+
+func TestBoxShrinkClamp(t *testing.T) {
+	b := Bx(0, 0, 0, 1, 6, 6)
+	if s := b.Inset(1); s != Bx(0, 1, 1, 0, 5, 5) {
+		t.Errorf("inset to %v", s)
+	}
+	if s := b.ShrinkClamp(1); s != ZB {
+		t.Errorf("shrunk to %v, expected ZB", s)
+	}
+
+	b = Bx(0, 0, 0, 4, 6, 6)
+	if s := b.ShrinkClamp(1); s != b.Inset(1) || s != Bx(1, 1, 1, 3, 5, 5) {
+		t.Errorf("shrunk to %v", s)
+	}
+}
+
+func TestBoxOverlapVolume(t *testing.T) {
+	b := Bx(0, 0, 0, 4, 4, 4)
+	tests := []struct {
+		s      Box
+		volume int
+	}{
+		{Bx(5, 5, 5, 8, 8, 8), 0},
+		{Bx(4, 0, 0, 6, 4, 4), 0},
+		{Bx(1, 1, 1, 3, 3, 2), 4},
+		{Bx(2, -1, 3, 6, 2, 5), 2 * 2 * 1},
+		{Bx(-2, -2, -2, 6, 6, 6), 64},
+	}
+
+	for _, tt := range tests {
+		if v := b.OverlapVolume(tt.s); v != tt.volume {
+			t.Errorf("overlap with %v is %d, expected %d", tt.s, v, tt.volume)
+		}
+		i := b.Intersect(tt.s)
+		if v := i.Dx() * i.Dy() * i.Dz(); v != tt.volume {
+			t.Errorf("intersection with %v has volume %d, expected %d", tt.s, v, tt.volume)
+		}
+	}
+}
+
+func TestBoxTiles(t *testing.T) {
+	b := Bx(-1, 0, 2, 9, 5, 5)
+	tiles := b.Tiles(Pt(4, 4, 2))
+	if len(tiles) != 3*2*2 {
+		t.Fatalf("got %d tiles, expected 12", len(tiles))
+	}
+
+	var union Box
+	var volume int
+	for i, ti := range tiles {
+		if !ti.In(b) {
+			t.Errorf("tile %v is outside %v", ti, b)
+		}
+		for _, tj := range tiles[i+1:] {
+			if ti.Overlaps(tj) {
+				t.Errorf("tiles %v and %v overlap", ti, tj)
+			}
+		}
+		union = union.Union(ti)
+		volume += ti.Dx() * ti.Dy() * ti.Dz()
+	}
+	if union != b || volume != b.Dx()*b.Dy()*b.Dz() {
+		t.Errorf("tiles cover %v with %d cells", union, volume)
+	}
+
+	if tiles := b.Tiles(Pt(0, 1, 1)); tiles != nil {
+		t.Errorf("got %d tiles for a zero size", len(tiles))
+	}
+}
+
+func TestBoxMirror(t *testing.T) {
+	b := Bx(1, 2, 3, 3, 5, 4)
+	if m := b.MirrorX(0); m != Bx(-3, 2, 3, -1, 5, 4) {
+		t.Errorf("mirrored across x=0 to %v", m)
+	}
+	if m := b.MirrorX(5); m != Bx(7, 2, 3, 9, 5, 4) {
+		t.Errorf("mirrored across x=5 to %v", m)
+	}
+	if m := b.MirrorY(2); m != Bx(1, -1, 3, 3, 2, 4) {
+		t.Errorf("mirrored across y=2 to %v", m)
+	}
+	if m := b.MirrorZ(4).MirrorZ(4); m != b {
+		t.Errorf("mirrored twice to %v", m)
+	}
+}
+
+func TestBoxIntersectOK(t *testing.T) {
+	b := Bx(0, 0, 0, 4, 4, 4)
+	if i, ok := b.IntersectOK(Bx(2, 2, 2, 6, 6, 6)); !ok || i != Bx(2, 2, 2, 4, 4, 4) {
+		t.Errorf("got %v, %v", i, ok)
+	}
+	if i, ok := b.IntersectOK(Bx(5, 0, 0, 6, 4, 4)); ok || i != ZB {
+		t.Errorf("got %v, %v for disjoint boxes", i, ok)
+	}
+	if _, ok := b.IntersectOK(Bx(4, 0, 0, 6, 4, 4)); ok {
+		t.Error("touching boxes reported as overlapping")
+	}
+}
+
+func TestBoxJSON(t *testing.T) {
+	b := Bx(-1, 2, 3, 4, 5, 6)
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != `{"min":[-1,2,3],"max":[4,5,6]}` {
+		t.Errorf("got %s", s)
+	}
+
+	var c Box
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c != b {
+		t.Errorf("got %v, expected %v", c, b)
+	}
+
+	var p Point
+	if err := json.Unmarshal([]byte("[1,2]"), &p); err == nil {
+		t.Error("short array decoded without error")
+	}
+}
+
+func TestBoxGrowToInclude(t *testing.T) {
+	points := []Point{Pt(2, 3, 4), Pt(-1, 5, 4), Pt(2, 0, 9)}
+	b := ZB
+	for _, p := range points {
+		b = b.GrowToInclude(p)
+		if !p.In(b) {
+			t.Errorf("%v is outside %v", p, b)
+		}
+	}
+	if expected := BoundingBox(points...); b != expected {
+		t.Errorf("got %v, expected %v", b, expected)
+	}
+	if b := ZB.GrowToInclude(Pt(0, 0, 0)); b != Bx(0, 0, 0, 1, 1, 1) {
+		t.Errorf("got %v for the origin, expected a single cell", b)
+	}
+}
@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"image/color"
+	"io"
+)
+
+var ErrInvalidData = errors.New("voxel: invalid data")
+
+type gobPaletted struct {
+	Bounds  Box
+	Palette []color.RGBA
+	Data    []uint8
+}
+
+// WriteGob writes p as a gzip-compressed gob stream.
+func (p *Paletted) WriteGob(w io.Writer) error {
+	g := gobPaletted{Bounds: p.bounds, Data: p.Data}
+	for _, c := range p.Palette {
+		g.Palette = append(g.Palette, color.RGBAModel.Convert(c).(color.RGBA))
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(&g); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadGob reads an image written by WriteGob.
+func ReadGob(r io.Reader) (*Paletted, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var g gobPaletted
+	if err := gob.NewDecoder(zr).Decode(&g); err != nil {
+		return nil, err
+	}
+
+	var pal color.Palette
+	for _, c := range g.Palette {
+		pal = append(pal, c)
+	}
+
+	p := NewPalettedAt(pal, g.Bounds)
+	if len(g.Data) != len(p.Data) {
+		return nil, ErrInvalidData
+	}
+	copy(p.Data, g.Data)
+	return p, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using the WriteGob
+// format, so a Paletted can be encoded directly with gob.
+func (p *Paletted) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.WriteGob(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The Transformer is
+// reset, as it is by ReadGob.
+func (p *Paletted) UnmarshalBinary(data []byte) error {
+	q, err := ReadGob(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*p = *q
+	return nil
+}
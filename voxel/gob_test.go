@@ -0,0 +1,63 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"bytes"
+	"encoding/gob"
+	"image/color/palette"
+	"testing"
+)
+
+func TestGob(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 32, 32, 32))
+	fillBox(img, Bx(4, 4, 4, 20, 20, 20), 3)
+	img.Set(31, 31, 31, 255)
+
+	var buf bytes.Buffer
+	if err := img.WriteGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(img.Data) {
+		t.Errorf("compressed to %d bytes, raw data is %d bytes", buf.Len(), len(img.Data))
+	}
+
+	p, err := ReadGob(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Bounds() != img.Bounds() {
+		t.Errorf("got bounds %v, expected %v", p.Bounds(), img.Bounds())
+	}
+	if !bytes.Equal(p.Data, img.Data) {
+		t.Error("data differs after round trip")
+	}
+	if len(p.Palette) != len(img.Palette) || p.GetColor(31, 31, 31) != img.GetColor(31, 31, 31) {
+		t.Error("palette differs after round trip")
+	}
+}
+
+func TestGobEncoder(t *testing.T) {
+	img := NewPalettedAt(palette.Plan9, Bx(-4, 0, 2, 12, 8, 10))
+	fillBox(img, Bx(0, 2, 4, 6, 6, 8), 7)
+	img.Set(-4, 0, 2, 200)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(img); err != nil {
+		t.Fatal(err)
+	}
+
+	var p Paletted
+	if err := gob.NewDecoder(&buf).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if !EqualPalette(&p, img) {
+		t.Error("image differs after round trip")
+	}
+	p.Set(0, 2, 4, 1)
+	if idx := p.Get(0, 2, 4); idx != 1 {
+		t.Errorf("got %d after Set, expected 1", idx)
+	}
+}
@@ -4,7 +4,10 @@
 
 package voxel
 
-import "image/color"
+import (
+	"image/color"
+	"sync"
+)
 
 type Image interface {
 	Bounds() Box
@@ -22,9 +25,12 @@ func Blit(dst, src Image, dp Point, sr Box) {
 	dr := Box{dp, sr.Size().Add(dp)}
 	b := dst.Bounds().Intersect(dr)
 
-	for z, sz := b.Min.Z, sr.Min.Z; z < b.Max.Z; z++ {
-		for y, sy := b.Min.Y, sr.Min.Y; y < b.Max.Y; y++ {
-			for x, sx := b.Min.X, sr.Min.X; x < b.Max.X; x++ {
+	// Skip the source voxels whose destination was clipped away.
+	sp := sr.Min.Add(b.Min.Sub(dr.Min))
+
+	for z, sz := b.Min.Z, sp.Z; z < b.Max.Z; z++ {
+		for y, sy := b.Min.Y, sp.Y; y < b.Max.Y; y++ {
+			for x, sx := b.Min.X, sp.X; x < b.Max.X; x++ {
 				dst.Set(x, y, z, src.Get(sx, sy, sz))
 				sx++
 			}
@@ -41,9 +47,12 @@ func BlitOp(dst, src Image, dp Point, sr Box, op Op) {
 	dr := Box{dp, sr.Size().Add(dp)}
 	b := dst.Bounds().Intersect(dr)
 
-	for z, sz := b.Min.Z, sr.Min.Z; z < b.Max.Z; z++ {
-		for y, sy := b.Min.Y, sr.Min.Y; y < b.Max.Y; y++ {
-			for x, sx := b.Min.X, sr.Min.X; x < b.Max.X; x++ {
+	// Skip the source voxels whose destination was clipped away.
+	sp := sr.Min.Add(b.Min.Sub(dr.Min))
+
+	for z, sz := b.Min.Z, sp.Z; z < b.Max.Z; z++ {
+		for y, sy := b.Min.Y, sp.Y; y < b.Max.Y; y++ {
+			for x, sx := b.Min.X, sp.X; x < b.Max.X; x++ {
 				op(dst, src, x, y, z, sx, sy, sz)
 				sx++
 			}
@@ -70,15 +79,35 @@ func NewPaletted(p color.Palette, b Box) *Paletted {
 	return img
 }
 
+// NewPalettedAt is like NewPaletted but keeps b.Min, so the image covers
+// exactly b and its voxels keep their world coordinates.
+func NewPalettedAt(p color.Palette, b Box) *Paletted {
+	img := NewPaletted(p, b.Sub(b.Min))
+	img.bounds = img.bounds.Add(b.Min)
+	return img
+}
+
 func (p *Paletted) Bounds() Box {
 	return p.bounds
 }
 
+// SetBounds resizes p to b.Max and clears it. When cap(p.Data) is large
+// enough the backing array is reused and zeroed in place, so any other
+// Paletted or slice sharing it is cleared as well. Give p a fresh Data first
+// if that memory must be kept.
 func (p *Paletted) SetBounds(b Box) {
 	x, y, z := p.Transformer(b.Max.X, b.Max.Y, b.Max.Z)
 	p.bounds = Box{ZP, Pt(x, y, z)}
 	sz := b.Max.X * b.Max.Y * b.Max.Z
-	p.Data = make([]uint8, sz)
+	if cap(p.Data) < sz {
+		p.Data = make([]uint8, sz)
+		return
+	}
+
+	p.Data = p.Data[:sz]
+	for i := range p.Data {
+		p.Data[i] = 0
+	}
 }
 
 func (p *Paletted) SetPalette(pal color.Palette) {
@@ -94,10 +123,143 @@ func (p *Paletted) Get(x, y, z int) uint8 {
 	return p.Data[p.Offset(x, y, z)]
 }
 
+// GetColor returns the palette color of the voxel at x, y, z, or
+// color.Transparent if the index is outside the palette.
 func (p *Paletted) GetColor(x, y, z int) color.Color {
-	return p.Palette[p.Get(x, y, z)]
+	if i := int(p.Get(x, y, z)); i < len(p.Palette) {
+		return p.Palette[i]
+	}
+	return color.Transparent
 }
 
 func (p *Paletted) Offset(x, y, z int) int {
-	return z*p.bounds.Max.X*p.bounds.Max.Y + y*p.bounds.Max.X + x
+	b := p.bounds
+	return (z-b.Min.Z)*b.Dx()*b.Dy() + (y-b.Min.Y)*b.Dx() + (x - b.Min.X)
+}
+
+// EachVoxel calls fn for every non-empty voxel, in storage order: x varies
+// fastest, then y, then z.
+func (p *Paletted) EachVoxel(fn func(x, y, z int, index uint8)) {
+	w, h := p.bounds.Dx(), p.bounds.Dy()
+	if w == 0 || h == 0 {
+		return
+	}
+
+	for i, index := range p.Data {
+		if index != 0 {
+			x, y, z := i%w, i/w%h, i/(w*h)
+			fn(x+p.bounds.Min.X, y+p.bounds.Min.Y, z+p.bounds.Min.Z, index)
+		}
+	}
+}
+
+// RGBA returns the colors of p in Offset order, ready to be uploaded as a
+// 3D texture. Empty voxels, and indices outside the palette, are fully
+// transparent.
+func (p *Paletted) RGBA() []color.RGBA {
+	colors := make([]color.RGBA, 256)
+	for i, c := range p.Palette {
+		if i > 0 && i < len(colors) {
+			colors[i] = color.RGBAModel.Convert(c).(color.RGBA)
+		}
+	}
+
+	out := make([]color.RGBA, len(p.Data))
+	for i, index := range p.Data {
+		out[i] = colors[index]
+	}
+	return out
+}
+
+// BlitParallel is like Blit but splits the destination Z range into slabs
+// copied by up to workers goroutines. The slabs do not overlap, so dst needs
+// no locking as long as writes to different voxels are independent, as
+// they are for Paletted.
+//
+// Starting the workers costs a few microseconds. On a single core the
+// BlitParallel benchmarks are slower than Blit up to 16³ voxels, and equal to
+// it within noise from 24³ up. The copy can only speed up on more cores.
+func BlitParallel(dst, src Image, dp Point, sr Box, workers int) {
+	sr = sr.Intersect(src.Bounds())
+	dr := Box{dp, sr.Size().Add(dp)}
+	b := dst.Bounds().Intersect(dr)
+
+	depth := b.Dz()
+	if workers > depth {
+		workers = depth
+	}
+	if workers <= 1 {
+		Blit(dst, src, dp, sr)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		z0, z1 := b.Min.Z+depth*i/workers, b.Min.Z+depth*(i+1)/workers
+		slab := sr
+		slab.Min.Z = sr.Min.Z + z0 - dr.Min.Z
+		slab.Max.Z = sr.Min.Z + z1 - dr.Min.Z
+
+		wg.Add(1)
+		go func(p Point, r Box) {
+			defer wg.Done()
+			Blit(dst, src, p, r)
+		}(Pt(dp.X, dp.Y, z0), slab)
+	}
+	wg.Wait()
+}
+
+func countNonEmpty(img Image, b Box) int {
+	var n int
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.Get(x, y, z) != 0 {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+// parallelCountMin is the smallest volume CountNonEmptyParallel splits up.
+const parallelCountMin = 32 * 32 * 32
+
+// CountNonEmptyParallel counts the non-empty voxels of img, splitting the Z
+// range across up to workers goroutines. Volumes under 32³ voxels are counted
+// serially, as starting the workers would cost more than it saves.
+func CountNonEmptyParallel(img Image, workers int) int {
+	b := img.Bounds()
+	if b.Empty() {
+		return 0
+	}
+
+	depth := b.Dz()
+	if workers > depth {
+		workers = depth
+	}
+	if workers <= 1 || b.Dx()*b.Dy()*depth < parallelCountMin {
+		return countNonEmpty(img, b)
+	}
+
+	counts := make([]int, workers)
+	var wg sync.WaitGroup
+	for i := range counts {
+		slab := b
+		slab.Min.Z, slab.Max.Z = b.Min.Z+depth*i/workers, b.Min.Z+depth*(i+1)/workers
+
+		wg.Add(1)
+		go func(i int, r Box) {
+			defer wg.Done()
+			counts[i] = countNonEmpty(img, r)
+		}(i, slab)
+	}
+	wg.Wait()
+
+	var n int
+	for _, c := range counts {
+		n += c
+	}
+	return n
 }
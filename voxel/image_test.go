@@ -0,0 +1,205 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+func TestEachVoxel(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 5, 4, 3))
+	fillBox(img, Bx(1, 1, 1, 3, 3, 2), 4)
+	img.Set(4, 3, 2, 9)
+
+	var n int
+	last := -1
+	img.EachVoxel(func(x, y, z int, index uint8) {
+		n++
+		if idx := img.Get(x, y, z); idx != index || idx == 0 {
+			t.Errorf("visited %v with index %d, expected %d", Pt(x, y, z), index, idx)
+		}
+		if o := img.Offset(x, y, z); o <= last {
+			t.Errorf("visited %v out of order", Pt(x, y, z))
+		} else {
+			last = o
+		}
+	})
+
+	if h := Histogram(img); n != 60-h[0] {
+		t.Errorf("visited %d voxels, expected %d", n, 60-h[0])
+	}
+}
+
+func TestBlitParallel(t *testing.T) {
+	src := NewPaletted(palette.Plan9, Bx(0, 0, 0, 16, 16, 16))
+	for i := range src.Data {
+		src.Data[i] = uint8(i)
+	}
+
+	a := NewPaletted(palette.Plan9, Bx(0, 0, 0, 20, 20, 20))
+	b := NewPaletted(palette.Plan9, Bx(0, 0, 0, 20, 20, 20))
+	Blit(a, src, Pt(3, 2, 5), Bx(1, 1, 1, 15, 15, 15))
+	BlitParallel(b, src, Pt(3, 2, 5), Bx(1, 1, 1, 15, 15, 15), 4)
+
+	for i := range a.Data {
+		if a.Data[i] != b.Data[i] {
+			t.Fatalf("data differs at offset %d", i)
+		}
+	}
+}
+
+func benchmarkBlit(b *testing.B, size, workers int) {
+	r := Bx(0, 0, 0, size, size, size)
+	src := NewPaletted(palette.Plan9, r)
+	dst := NewPaletted(palette.Plan9, r)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if workers == 0 {
+			Blit(dst, src, ZP, r)
+		} else {
+			BlitParallel(dst, src, ZP, r, workers)
+		}
+	}
+}
+
+func BenchmarkBlit8(b *testing.B)           { benchmarkBlit(b, 8, 0) }
+func BenchmarkBlitParallel8(b *testing.B)   { benchmarkBlit(b, 8, 8) }
+func BenchmarkBlit16(b *testing.B)          { benchmarkBlit(b, 16, 0) }
+func BenchmarkBlitParallel16(b *testing.B)  { benchmarkBlit(b, 16, 8) }
+func BenchmarkBlit24(b *testing.B)          { benchmarkBlit(b, 24, 0) }
+func BenchmarkBlitParallel24(b *testing.B)  { benchmarkBlit(b, 24, 8) }
+func BenchmarkBlit32(b *testing.B)          { benchmarkBlit(b, 32, 0) }
+func BenchmarkBlitParallel32(b *testing.B)  { benchmarkBlit(b, 32, 8) }
+func BenchmarkBlit64(b *testing.B)          { benchmarkBlit(b, 64, 0) }
+func BenchmarkBlitParallel64(b *testing.B)  { benchmarkBlit(b, 64, 8) }
+func BenchmarkBlit256(b *testing.B)         { benchmarkBlit(b, 256, 0) }
+func BenchmarkBlitParallel256(b *testing.B) { benchmarkBlit(b, 256, 8) }
+
+func TestBlitNegativeOffset(t *testing.T) {
+	src := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 2, 2))
+	for z := 0; z < 2; z++ {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				src.Set(x, y, z, uint8(10+x))
+			}
+		}
+	}
+
+	dst := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 2, 2))
+	Blit(dst, src, Pt(-2, 0, 0), src.Bounds())
+
+	for x, expected := range []uint8{12, 13, 0, 0} {
+		if idx := dst.Get(x, 1, 1); idx != expected {
+			t.Errorf("column %d is %d, expected %d", x, idx, expected)
+		}
+	}
+
+	op := func(dst, src Image, dx, dy, dz, sx, sy, sz int) {
+		dst.Set(dx, dy, dz, src.Get(sx, sy, sz)+1)
+	}
+	BlitOp(dst, src, Pt(-3, 0, -1), src.Bounds(), op)
+	if idx := dst.Get(0, 0, 0); idx != 14 {
+		t.Errorf("got %d, expected 14", idx)
+	}
+}
+
+func TestPalettedRGBA(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 3))
+	img.Set(0, 0, 0, 7)
+	img.Set(2, 1, 2, 200)
+	img.Set(1, 2, 0, 255)
+
+	colors := img.RGBA()
+	if len(colors) != len(img.Data) {
+		t.Fatalf("got %d colors, expected %d", len(colors), len(img.Data))
+	}
+
+	for _, p := range []Point{Pt(0, 0, 0), Pt(2, 1, 2), Pt(1, 2, 0)} {
+		expected := color.RGBAModel.Convert(palette.Plan9[img.Get(p.X, p.Y, p.Z)])
+		if c := colors[img.Offset(p.X, p.Y, p.Z)]; c != expected {
+			t.Errorf("voxel %v has color %v, expected %v", p, c, expected)
+		}
+	}
+	if c := colors[img.Offset(1, 1, 1)]; c != (color.RGBA{}) {
+		t.Errorf("empty voxel has color %v, expected transparent", c)
+	}
+}
+
+func TestGetColorShortPalette(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.White}
+	img := NewPaletted(pal, Bx(0, 0, 0, 2, 2, 2))
+	img.Set(0, 0, 0, 1)
+	img.Set(1, 1, 1, 200)
+
+	if c := img.GetColor(0, 0, 0); c != color.White {
+		t.Errorf("got %v, expected white", c)
+	}
+	if c := img.GetColor(1, 1, 1); c != color.Transparent {
+		t.Errorf("got %v, expected transparent", c)
+	}
+
+	img.SetPalette(nil)
+	if c := img.GetColor(0, 0, 0); c != color.Transparent {
+		t.Errorf("got %v with no palette, expected transparent", c)
+	}
+}
+
+func TestNewPalettedAt(t *testing.T) {
+	b := Bx(-2, 3, 5, 2, 6, 7)
+	img := NewPalettedAt(palette.Plan9, b)
+	if img.Bounds() != b {
+		t.Fatalf("got bounds %v, expected %v", img.Bounds(), b)
+	}
+	if len(img.Data) != 4*3*2 {
+		t.Fatalf("got %d bytes of data, expected %d", len(img.Data), 4*3*2)
+	}
+
+	img.Set(-2, 3, 5, 1)
+	img.Set(1, 5, 6, 2)
+	if img.Data[0] != 1 || img.Data[len(img.Data)-1] != 2 {
+		t.Error("corners are not stored at the ends of the data")
+	}
+	if idx := img.Get(1, 5, 6); idx != 2 {
+		t.Errorf("got %d, expected 2", idx)
+	}
+}
+
+func TestCountNonEmptyParallel(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, size := range []int{8, 48} {
+		img := NewPaletted(palette.Plan9, Bx(0, 0, 0, size, size, size))
+		for i := range img.Data {
+			if rnd.Intn(3) == 0 {
+				img.Data[i] = uint8(1 + rnd.Intn(255))
+			}
+		}
+
+		expected := countNonEmpty(img, img.Bounds())
+		for _, workers := range []int{1, 3, 8} {
+			if n := CountNonEmptyParallel(img, workers); n != expected {
+				t.Errorf("size %d with %d workers counted %d, expected %d", size, workers, n, expected)
+			}
+		}
+	}
+}
+
+func benchmarkCount(b *testing.B, workers int) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 128, 128, 128))
+	for i := range img.Data {
+		img.Data[i] = uint8(i % 3)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CountNonEmptyParallel(img, workers)
+	}
+}
+
+func BenchmarkCountNonEmpty(b *testing.B)         { benchmarkCount(b, 1) }
+func BenchmarkCountNonEmptyParallel(b *testing.B) { benchmarkCount(b, 8) }
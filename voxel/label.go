@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+var cornerNeighbors = func() []Point {
+	var n []Point
+	for z := -1; z <= 1; z++ {
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				if x != 0 || y != 0 || z != 0 {
+					n = append(n, Point{x, y, z})
+				}
+			}
+		}
+	}
+	return n
+}()
+
+func neighborhood(connectivity int) []Point {
+	switch connectivity {
+	case 6:
+		return faceNeighbors[:]
+	case 26:
+		return cornerNeighbors
+	default:
+		panic("voxel: connectivity must be 6 or 26")
+	}
+}
+
+// Label assigns each non-zero voxel the id of the component it belongs to,
+// using 6 or 26 connectivity. Labels are laid out like Paletted.Offset,
+// relative to the image bounds. Empty voxels are labeled 0 and components
+// are numbered from 1 to count.
+func Label(img Image, connectivity int) (labels []int, count int) {
+	neighbors := neighborhood(connectivity)
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, 0
+	}
+
+	w, h := b.Dx(), b.Dy()
+	offset := func(p Point) int {
+		p = p.Sub(b.Min)
+		return p.Z*w*h + p.Y*w + p.X
+	}
+
+	labels = make([]int, w*h*b.Dz())
+	var stack []Point
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				if img.Get(x, y, z) == 0 || labels[offset(p)] != 0 {
+					continue
+				}
+
+				count++
+				labels[offset(p)] = count
+				stack = append(stack[:0], p)
+
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+
+					for _, d := range neighbors {
+						q := p.Add(d)
+						if !q.In(b) || img.Get(q.X, q.Y, q.Z) == 0 {
+							continue
+						}
+						if o := offset(q); labels[o] == 0 {
+							labels[o] = count
+							stack = append(stack, q)
+						}
+					}
+				}
+			}
+		}
+	}
+	return labels, count
+}
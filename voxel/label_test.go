@@ -0,0 +1,39 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 8, 8, 8))
+
+	fillBox(img, Bx(0, 0, 0, 3, 3, 3), 1)
+	fillBox(img, Bx(4, 4, 4, 7, 7, 7), 2)
+
+	for _, connectivity := range []int{6, 26} {
+		labels, count := Label(img, connectivity)
+		if count != 2 {
+			t.Errorf("%d-connected: got %d components, expected 2", connectivity, count)
+		}
+		if a, b := labels[img.Offset(1, 1, 1)], labels[img.Offset(5, 5, 5)]; a == 0 || b == 0 || a == b {
+			t.Errorf("%d-connected: unexpected labels %d and %d", connectivity, a, b)
+		}
+		if l := labels[img.Offset(3, 3, 3)]; l != 0 {
+			t.Errorf("%d-connected: empty voxel labeled %d", connectivity, l)
+		}
+	}
+
+	// The cubes touch diagonally once this corner is filled.
+	img.Set(3, 3, 3, 3)
+	if _, count := Label(img, 6); count != 3 {
+		t.Errorf("6-connected: got %d components, expected 3", count)
+	}
+	if _, count := Label(img, 26); count != 1 {
+		t.Errorf("26-connected: got %d components, expected 1", count)
+	}
+}
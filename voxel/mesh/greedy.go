@@ -0,0 +1,132 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import "github.com/andreas-jonsson/voxel/voxel"
+
+// Quad is a rectangular voxel face. Corners are ordered counter-clockwise
+// when seen from the side the normal points to.
+type Quad struct {
+	Corners [4]voxel.Point
+	Normal  voxel.Point
+	Index   uint8
+}
+
+func axis(p *voxel.Point, d int) *int {
+	switch d {
+	case 0:
+		return &p.X
+	case 1:
+		return &p.Y
+	default:
+		return &p.Z
+	}
+}
+
+type face struct {
+	index uint8
+	back  bool
+}
+
+func makeQuad(p voxel.Point, d, w, h int, f face) Quad {
+	var du, dv, normal voxel.Point
+	*axis(&du, (d+1)%3) = w
+	*axis(&dv, (d+2)%3) = h
+
+	quad := Quad{Index: f.index}
+	if f.back {
+		*axis(&normal, d) = -1
+		quad.Corners = [4]voxel.Point{p, p.Add(dv), p.Add(du).Add(dv), p.Add(du)}
+	} else {
+		*axis(&normal, d) = 1
+		quad.Corners = [4]voxel.Point{p, p.Add(du), p.Add(du).Add(dv), p.Add(dv)}
+	}
+	quad.Normal = normal
+	return quad
+}
+
+// GreedyMesh returns the visible faces of img, merging adjacent coplanar
+// faces of the same index into larger quads. Faces between two non-empty
+// voxels are culled, and the bounds count as empty.
+func GreedyMesh(img voxel.Image) []Quad {
+	var quads []Quad
+	b := img.Bounds()
+	size := b.Size()
+
+	get := func(p voxel.Point) uint8 {
+		if p = p.Add(b.Min); p.In(b) {
+			return img.Get(p.X, p.Y, p.Z)
+		}
+		return 0
+	}
+
+	for d := 0; d < 3; d++ {
+		u, v := (d+1)%3, (d+2)%3
+		nd, nu, nv := *axis(&size, d), *axis(&size, u), *axis(&size, v)
+		mask := make([]face, nu*nv)
+
+		var q voxel.Point
+		*axis(&q, d) = 1
+
+		for i := -1; i < nd; i++ {
+			for n := range mask {
+				var p voxel.Point
+				*axis(&p, d) = i
+				*axis(&p, u) = n % nu
+				*axis(&p, v) = n / nu
+
+				a, c := get(p), get(p.Add(q))
+				switch {
+				case a != 0 && c == 0:
+					mask[n] = face{a, false}
+				case a == 0 && c != 0:
+					mask[n] = face{c, true}
+				default:
+					mask[n] = face{}
+				}
+			}
+
+			for j := 0; j < nv; j++ {
+				for k := 0; k < nu; {
+					f := mask[j*nu+k]
+					if f.index == 0 {
+						k++
+						continue
+					}
+
+					w := 1
+					for k+w < nu && mask[j*nu+k+w] == f {
+						w++
+					}
+
+					h := 1
+				grow:
+					for j+h < nv {
+						for l := 0; l < w; l++ {
+							if mask[(j+h)*nu+k+l] != f {
+								break grow
+							}
+						}
+						h++
+					}
+
+					var p voxel.Point
+					*axis(&p, d) = i + 1
+					*axis(&p, u) = k
+					*axis(&p, v) = j
+					quads = append(quads, makeQuad(p.Add(b.Min), d, w, h, f))
+
+					for l := 0; l < h; l++ {
+						for m := 0; m < w; m++ {
+							mask[(j+l)*nu+k+m] = face{}
+						}
+					}
+					k += w
+				}
+			}
+		}
+	}
+	return quads
+}
@@ -0,0 +1,56 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestGreedyMesh(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 8, 8, 1))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, 0, 3)
+		}
+	}
+
+	quads := GreedyMesh(img)
+	if len(quads) != 6 {
+		t.Fatalf("got %d quads, expected 6", len(quads))
+	}
+
+	var top *Quad
+	for i, q := range quads {
+		if q.Index != 3 {
+			t.Errorf("quad has index %d, expected 3", q.Index)
+		}
+		if q.Normal == voxel.Pt(0, 0, 1) {
+			top = &quads[i]
+		}
+	}
+
+	if top == nil {
+		t.Fatal("missing top face")
+	}
+	expected := [4]voxel.Point{voxel.Pt(0, 0, 1), voxel.Pt(8, 0, 1), voxel.Pt(8, 8, 1), voxel.Pt(0, 8, 1)}
+	if top.Corners != expected {
+		t.Errorf("top face is %v, expected %v", top.Corners, expected)
+	}
+}
+
+func TestGreedyMeshCulling(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 2, 1, 1))
+	img.Set(0, 0, 0, 1)
+	img.Set(1, 0, 0, 2)
+
+	for _, q := range GreedyMesh(img) {
+		if q.Corners[0].X == 1 && q.Normal.X != 0 {
+			t.Errorf("internal face was not culled: %v", q)
+		}
+	}
+}
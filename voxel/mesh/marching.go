@@ -0,0 +1,155 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import "github.com/andreas-jonsson/voxel/voxel"
+
+// Cube corners and edges use Paul Bourke's numbering, so the case index and
+// edgeTable match the classic marching cubes tables.
+var (
+	cubeCorners = [8]voxel.Point{
+		voxel.Pt(0, 0, 0), voxel.Pt(1, 0, 0), voxel.Pt(1, 1, 0), voxel.Pt(0, 1, 0),
+		voxel.Pt(0, 0, 1), voxel.Pt(1, 0, 1), voxel.Pt(1, 1, 1), voxel.Pt(0, 1, 1),
+	}
+	cubeEdges = [12][2]int{
+		{0, 1}, {1, 2}, {2, 3}, {3, 0},
+		{4, 5}, {5, 6}, {6, 7}, {7, 4},
+		{0, 4}, {1, 5}, {2, 6}, {3, 7},
+	}
+	// cubeFaces lists the corners of each face counter-clockwise as seen
+	// from outside the cube.
+	cubeFaces = [6][4]int{
+		{0, 4, 7, 3}, {1, 2, 6, 5},
+		{0, 1, 5, 4}, {3, 7, 6, 2},
+		{0, 3, 2, 1}, {4, 5, 6, 7},
+	}
+)
+
+// edgeTable holds, for each case, a bit per edge crossed by the surface,
+// and triTable the edges whose crossings form the triangles of the case.
+// Both are generated at init by buildCubeTables. edgeTable comes out equal
+// to Bourke's, but triTable is not his table: it fans each contour traced
+// around the faces of the cube, so triangles and their order can differ.
+// Generating it avoids copying 256 hand-written rows and guarantees every
+// triangle winds outward. Faces with two diagonal inside corners keep those
+// corners apart, which is consistent between neighboring cubes, so the mesh
+// has no cracks.
+var edgeTable, triTable = buildCubeTables()
+
+func buildCubeTables() (edges [256]uint16, tris [256][]int) {
+	var edgeOf [8][8]int
+	for i, e := range cubeEdges {
+		edgeOf[e[0]][e[1]], edgeOf[e[1]][e[0]] = i, i
+	}
+
+	for c := 0; c < 256; c++ {
+		inside := func(corner int) bool { return c&(1<<uint(corner)) != 0 }
+
+		for i, e := range cubeEdges {
+			if inside(e[0]) != inside(e[1]) {
+				edges[c] |= 1 << uint(i)
+			}
+		}
+
+		// Walking each face boundary, the contour runs from where it
+		// leaves an inside run of corners back to where it entered it.
+		next := make(map[int]int)
+		var order []int
+		for _, f := range cubeFaces {
+			for k := 0; k < 4; k++ {
+				a, b := f[k], f[(k+1)%4]
+				if !inside(a) || inside(b) {
+					continue
+				}
+				// Find the edge where this inside run started.
+				start := k
+				for inside(f[(start+3)%4]) {
+					start = (start + 3) % 4
+				}
+				e := edgeOf[a][b]
+				next[e] = edgeOf[f[(start+3)%4]][f[start]]
+				order = append(order, e)
+			}
+		}
+
+		for _, first := range order {
+			if _, ok := next[first]; !ok {
+				continue
+			}
+			loop := []int{first}
+			for {
+				e := next[loop[len(loop)-1]]
+				delete(next, loop[len(loop)-1])
+				if e == loop[0] {
+					break
+				}
+				loop = append(loop, e)
+			}
+			for i := 1; i+1 < len(loop); i++ {
+				tris[c] = append(tris[c], loop[0], loop[i+1], loop[i])
+			}
+		}
+	}
+	return
+}
+
+// MarchingCubes extracts the iso surface of field, laid out like
+// Paletted.Offset over b as returned by voxel.DistanceField. Values below
+// iso are inside. Samples sit at the cell centers, and vertices are placed
+// by linear interpolation along the crossed cube edges. Triangles wind
+// counter-clockwise seen from outside.
+func MarchingCubes(field []float32, b voxel.Box, iso float32) []Triangle {
+	w, h := b.Dx(), b.Dy()
+	sample := func(p voxel.Point) float32 {
+		return field[p.Z*w*h+p.Y*w+p.X]
+	}
+
+	vertex := func(p, q voxel.Point) voxel.Pointf {
+		// Always interpolate from the lower corner, so the cubes sharing
+		// an edge produce the exact same vertex.
+		if q.X < p.X || q.Y < p.Y || q.Z < p.Z {
+			p, q = q, p
+		}
+		vp, vq := sample(p), sample(q)
+		t := 0.5
+		if vp != vq {
+			t = float64((iso - vp) / (vq - vp))
+		}
+		pf := voxel.Ptf(float64(p.X)+0.5, float64(p.Y)+0.5, float64(p.Z)+0.5)
+		d := voxel.Ptf(float64(q.X-p.X), float64(q.Y-p.Y), float64(q.Z-p.Z))
+		return pf.Add(d.Mul(t)).Add(voxel.Ptf(float64(b.Min.X), float64(b.Min.Y), float64(b.Min.Z)))
+	}
+
+	var tris []Triangle
+	for z := 0; z < b.Dz()-1; z++ {
+		for y := 0; y < h-1; y++ {
+			for x := 0; x < w-1; x++ {
+				p := voxel.Pt(x, y, z)
+				c := 0
+				for i, corner := range cubeCorners {
+					if sample(p.Add(corner)) < iso {
+						c |= 1 << uint(i)
+					}
+				}
+				if edgeTable[c] == 0 {
+					continue
+				}
+
+				var verts [12]voxel.Pointf
+				for i, e := range cubeEdges {
+					if edgeTable[c]&(1<<uint(i)) != 0 {
+						verts[i] = vertex(p.Add(cubeCorners[e[0]]), p.Add(cubeCorners[e[1]]))
+					}
+				}
+
+				t := triTable[c]
+				for i := 0; i+2 < len(t); i += 3 {
+					tris = append(tris, Triangle{verts[t[i]], verts[t[i+1]], verts[t[i+2]]})
+				}
+			}
+		}
+	}
+	return tris
+}
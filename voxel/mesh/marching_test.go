@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestCubeTables(t *testing.T) {
+	// Spot check against the classic tables.
+	for c, expected := range map[int]uint16{0: 0, 1: 0x109, 2: 0x203, 3: 0x30a, 0x80: 0x8c0, 0xff: 0} {
+		if edgeTable[c] != expected {
+			t.Errorf("edgeTable[%#x] is %#x, expected %#x", c, edgeTable[c], expected)
+		}
+	}
+
+	for c := 0; c < 256; c++ {
+		used := uint16(0)
+		for _, e := range triTable[c] {
+			used |= 1 << uint(e)
+		}
+		if used != edgeTable[c] {
+			t.Errorf("case %#x uses edges %#x, expected %#x", c, used, edgeTable[c])
+		}
+	}
+}
+
+func TestMarchingCubes(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 20, 20, 20))
+	voxel.DrawSphere(img, voxel.Pt(10, 10, 10), 6, 1)
+
+	tris := MarchingCubes(voxel.DistanceField(img), img.Bounds(), 0)
+	if len(tris) == 0 {
+		t.Fatal("no triangles")
+	}
+
+	center := voxel.Ptf(10.5, 10.5, 10.5)
+	edges := make(map[[2]voxel.Pointf]int)
+	for _, tri := range tris {
+		for i, v := range tri {
+			if r := v.Sub(center).Len(); r < 5 || r > 8 {
+				t.Fatalf("vertex %v is %g from the center", v, r)
+			}
+			edges[[2]voxel.Pointf{v, tri[(i+1)%3]}]++
+		}
+
+		// Triangles face away from the center.
+		n := cross(tri[1].Sub(tri[0]), tri[2].Sub(tri[0]))
+		if n.Len() > 1e-9 && dot(n, tri[0].Sub(center)) < 0 {
+			t.Fatalf("triangle %v faces inwards", tri)
+		}
+	}
+
+	// Every directed edge must be matched by its reverse exactly once.
+	for e, n := range edges {
+		if n != 1 || edges[[2]voxel.Pointf{e[1], e[0]}] != 1 {
+			t.Fatalf("edge %v is used %d times, its reverse %d times", e, n, edges[[2]voxel.Pointf{e[1], e[0]}])
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+var normals = [6]voxel.Point{
+	voxel.Pt(-1, 0, 0), voxel.Pt(1, 0, 0),
+	voxel.Pt(0, -1, 0), voxel.Pt(0, 1, 0),
+	voxel.Pt(0, 0, -1), voxel.Pt(0, 0, 1),
+}
+
+func normalIndex(n voxel.Point) int {
+	for i, m := range normals {
+		if m == n {
+			return i
+		}
+	}
+	return -1
+}
+
+func paletteColor(pal color.Palette, index uint8) color.RGBA {
+	if int(index) < len(pal) {
+		return color.RGBAModel.Convert(pal[index]).(color.RGBA)
+	}
+	return color.RGBA{255, 255, 255, 255}
+}
+
+// OBJOptions control WriteOBJWithOptions.
+type OBJOptions struct {
+	// BakeAO writes a vertex color per face corner, darkening the palette
+	// color by voxel.CornerOcclusion down to half brightness. Faces are not
+	// merged, so the occlusion can vary across flat surfaces, and vertices
+	// are not shared. The colors follow the common "v x y z r g b"
+	// extension; viewers that ignore it still get the materials.
+	BakeAO bool
+}
+
+// WriteOBJ writes the greedy mesh of img as a Wavefront OBJ to w, with one
+// material per used palette index. The materials are written to mtl, which
+// may be nil to skip them. Callers referencing the materials from the OBJ
+// should prepend a mtllib statement naming the file mtl is saved as.
+func WriteOBJ(w, mtl io.Writer, img voxel.Image, pal color.Palette, scale float64) error {
+	return WriteOBJWithOptions(w, mtl, img, pal, scale, OBJOptions{})
+}
+
+// quadOcclusion returns the occlusion of the corners of a unit quad from
+// Faces, in the order of q.Corners.
+func quadOcclusion(img voxel.Image, q Quad) [4]float64 {
+	f := normalIndex(q.Normal)
+	if f%2 == 0 {
+		// Back faces list their corners in the opposite direction.
+		ao := voxel.CornerOcclusion(img, q.Corners[0], f)
+		return [4]float64{ao[0], ao[3], ao[2], ao[1]}
+	}
+	return voxel.CornerOcclusion(img, q.Corners[0].Sub(q.Normal), f)
+}
+
+// WriteOBJWithOptions is like WriteOBJ but lets the caller bake ambient
+// occlusion into the mesh.
+func WriteOBJWithOptions(w, mtl io.Writer, img voxel.Image, pal color.Palette, scale float64, opts OBJOptions) error {
+	var quads []Quad
+	if opts.BakeAO {
+		quads = Faces(img)
+	} else {
+		quads = GreedyMesh(img)
+	}
+	sort.SliceStable(quads, func(i, j int) bool {
+		return quads[i].Index < quads[j].Index
+	})
+
+	bw := bufio.NewWriter(w)
+	vertices := make(map[voxel.Point]int)
+	faces := make([][4]int, len(quads))
+	var numVertices int
+
+	for i, q := range quads {
+		if opts.BakeAO {
+			ao := quadOcclusion(img, q)
+			c := paletteColor(pal, q.Index)
+			for j, p := range q.Corners {
+				k := (1 + ao[j]) / 2 / 255
+				numVertices++
+				faces[i][j] = numVertices
+				fmt.Fprintf(bw, "v %g %g %g %g %g %g\n", float64(p.X)*scale, float64(p.Y)*scale, float64(p.Z)*scale,
+					float64(c.R)*k, float64(c.G)*k, float64(c.B)*k)
+			}
+			continue
+		}
+
+		for j, p := range q.Corners {
+			if _, ok := vertices[p]; !ok {
+				numVertices++
+				vertices[p] = numVertices
+				fmt.Fprintf(bw, "v %g %g %g\n", float64(p.X)*scale, float64(p.Y)*scale, float64(p.Z)*scale)
+			}
+			faces[i][j] = vertices[p]
+		}
+	}
+
+	for _, n := range normals {
+		fmt.Fprintf(bw, "vn %d %d %d\n", n.X, n.Y, n.Z)
+	}
+
+	var used []uint8
+	for i, q := range quads {
+		if i == 0 || q.Index != quads[i-1].Index {
+			used = append(used, q.Index)
+			fmt.Fprintf(bw, "usemtl voxel%d\n", q.Index)
+		}
+
+		n := normalIndex(q.Normal) + 1
+		f := faces[i]
+		fmt.Fprintf(bw, "f %d//%d %d//%d %d//%d %d//%d\n", f[0], n, f[1], n, f[2], n, f[3], n)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	if mtl == nil {
+		return nil
+	}
+
+	bw = bufio.NewWriter(mtl)
+	for _, index := range used {
+		c := paletteColor(pal, index)
+		fmt.Fprintf(bw, "newmtl voxel%d\n", index)
+		fmt.Fprintf(bw, "Kd %g %g %g\n", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+		fmt.Fprintf(bw, "d %g\n\n", float64(c.A)/255)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bytes"
+	"image/color"
+	"image/color/palette"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func countPrefix(s, prefix string) int {
+	var n int
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriteOBJ(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 3, 3, 3))
+	img.Set(1, 1, 1, 42)
+
+	var obj, mtl bytes.Buffer
+	if err := WriteOBJ(&obj, &mtl, img, palette.Plan9, 0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countPrefix(obj.String(), "v "); n != 8 {
+		t.Errorf("got %d vertices, expected 8", n)
+	}
+	if n := countPrefix(obj.String(), "f "); n != 6 {
+		t.Errorf("got %d faces, expected 6", n)
+	}
+	if n := countPrefix(mtl.String(), "newmtl voxel42"); n != 1 {
+		t.Errorf("got %d materials, expected 1", n)
+	}
+}
+
+func TestWriteOBJAmbientOcclusion(t *testing.T) {
+	// Two floor voxels with a wall voxel on the second, so the top face of
+	// the first has an interior corner along x 1.
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 2, 1, 2))
+	img.Set(0, 0, 0, 1)
+	img.Set(1, 0, 0, 1)
+	img.Set(1, 0, 1, 1)
+	pal := color.Palette{color.Transparent, color.White}
+
+	var obj bytes.Buffer
+	if err := WriteOBJWithOptions(&obj, nil, img, pal, 1, OBJOptions{BakeAO: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var vertices [][6]float64
+	var top []int
+	for _, line := range strings.Split(obj.String(), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 7 && fields[0] == "v":
+			var v [6]float64
+			for i := range v {
+				v[i], _ = strconv.ParseFloat(fields[i+1], 64)
+			}
+			vertices = append(vertices, v)
+		case len(fields) == 5 && fields[0] == "f" && strings.HasSuffix(fields[1], "//6"):
+			for _, f := range fields[1:] {
+				i, _ := strconv.Atoi(strings.Split(f, "/")[0])
+				if v := vertices[i-1]; v[2] == 1 && v[0] <= 1 {
+					top = append(top, i-1)
+				}
+			}
+		}
+	}
+	if n := countPrefix(obj.String(), "f "); len(vertices) != 4*n {
+		t.Fatalf("got %d vertices for %d faces", len(vertices), n)
+	}
+	if len(top) != 4 {
+		t.Fatalf("found %d corners of the top face, expected 4", len(top))
+	}
+
+	for _, i := range top {
+		for _, j := range top {
+			inner, open := vertices[i], vertices[j]
+			if inner[0] == 1 && open[0] == 0 && inner[3] >= open[3] {
+				t.Errorf("interior corner %v is not darker than open corner %v", inner, open)
+			}
+		}
+	}
+}
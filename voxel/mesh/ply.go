@@ -0,0 +1,75 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+type (
+	plyVertex struct {
+		X, Y, Z    float32
+		R, G, B, A uint8
+	}
+
+	plyFace struct {
+		Count   uint8
+		Indices [4]int32
+	}
+)
+
+// WritePLY writes the greedy mesh of img as a PLY file with per-vertex
+// colors taken from pal. The body is little-endian binary if bin is set,
+// otherwise ASCII.
+func WritePLY(w io.Writer, img voxel.Image, pal color.Palette, bin bool) error {
+	quads := GreedyMesh(img)
+	bw := bufio.NewWriter(w)
+
+	format := "ascii"
+	if bin {
+		format = "binary_little_endian"
+	}
+
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\n", format)
+	fmt.Fprintf(bw, "element vertex %d\n", len(quads)*4)
+	fmt.Fprint(bw, "property float x\nproperty float y\nproperty float z\n")
+	fmt.Fprint(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	fmt.Fprintf(bw, "element face %d\n", len(quads))
+	fmt.Fprint(bw, "property list uchar int vertex_indices\nend_header\n")
+
+	for _, q := range quads {
+		c := paletteColor(pal, q.Index)
+		for _, p := range q.Corners {
+			v := plyVertex{float32(p.X), float32(p.Y), float32(p.Z), c.R, c.G, c.B, c.A}
+			if bin {
+				if err := binary.Write(bw, binary.LittleEndian, &v); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(bw, "%g %g %g %d %d %d %d\n", v.X, v.Y, v.Z, v.R, v.G, v.B, v.A)
+			}
+		}
+	}
+
+	for i := range quads {
+		n := int32(i * 4)
+		f := plyFace{4, [4]int32{n, n + 1, n + 2, n + 3}}
+		if bin {
+			if err := binary.Write(bw, binary.LittleEndian, &f); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(bw, "4 %d %d %d %d\n", f.Indices[0], f.Indices[1], f.Indices[2], f.Indices[3])
+		}
+	}
+
+	return bw.Flush()
+}
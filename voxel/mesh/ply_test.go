@@ -0,0 +1,68 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color/palette"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func readPLYHeader(t *testing.T, r *bufio.Reader) (vertices, faces int) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "end_header" {
+			return
+		}
+		fmt.Sscanf(line, "element vertex %d", &vertices)
+		fmt.Sscanf(line, "element face %d", &faces)
+	}
+}
+
+func TestWritePLY(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 4, 4, 4))
+	img.Set(0, 0, 0, 1)
+	img.Set(2, 2, 2, 2)
+
+	var buf bytes.Buffer
+	if err := WritePLY(&buf, img, palette.Plan9, false); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(&buf)
+	vertices, faces := readPLYHeader(t, r)
+	if vertices != 48 || faces != 12 {
+		t.Errorf("header declares %d vertices and %d faces", vertices, faces)
+	}
+
+	body, _ := io.ReadAll(r)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != vertices+faces {
+		t.Errorf("body has %d lines, expected %d", len(lines), vertices+faces)
+	}
+
+	buf.Reset()
+	if err := WritePLY(&buf, img, palette.Plan9, true); err != nil {
+		t.Fatal(err)
+	}
+
+	r = bufio.NewReader(&buf)
+	vertices, faces = readPLYHeader(t, r)
+	body, _ = io.ReadAll(r)
+	if n := vertices*16 + faces*17; len(body) != n {
+		t.Errorf("body has %d bytes, expected %d", len(body), n)
+	}
+}
@@ -0,0 +1,28 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import "github.com/andreas-jonsson/voxel/voxel"
+
+// EmptySlices reports, for every slice of p along each axis, whether it holds
+// no non-empty voxels. Slices are numbered from the bounds minimum, so
+// z[i] covers the voxels at p.Bounds().Min.Z+i.
+func EmptySlices(p *voxel.Paletted) (x, y, z []bool) {
+	b := p.Bounds()
+	x, y, z = make([]bool, b.Dx()), make([]bool, b.Dy()), make([]bool, b.Dz())
+	for _, s := range [][]bool{x, y, z} {
+		for i := range s {
+			s[i] = true
+		}
+	}
+
+	for c := p.Cursor(); c.Next(); {
+		if c.Get() != 0 {
+			pt := c.Pos().Sub(b.Min)
+			x[pt.X], y[pt.Y], z[pt.Z] = false, false, false
+		}
+	}
+	return x, y, z
+}
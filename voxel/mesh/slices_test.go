@@ -0,0 +1,34 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"image/color/palette"
+	"reflect"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestEmptySlices(t *testing.T) {
+	// An L in the XY plane at z 1, leaving the last column and row empty.
+	img := voxel.NewPalettedAt(palette.Plan9, voxel.Bx(1, 0, 0, 6, 5, 3))
+	voxel.DrawBox(img, voxel.Bx(1, 0, 1, 5, 1, 2), 2, true)
+	voxel.DrawBox(img, voxel.Bx(1, 0, 1, 2, 4, 2), 2, true)
+
+	x, y, z := EmptySlices(img)
+	for _, c := range []struct {
+		name        string
+		got, expect []bool
+	}{
+		{"x", x, []bool{false, false, false, false, true}},
+		{"y", y, []bool{false, false, false, false, true}},
+		{"z", z, []bool{true, false, true}},
+	} {
+		if !reflect.DeepEqual(c.got, c.expect) {
+			t.Errorf("%s slices are %v, expected %v", c.name, c.got, c.expect)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+type stlTriangle struct {
+	Normal    [3]float32
+	Vertices  [3][3]float32
+	Attribute uint16
+}
+
+// Faces returns one unit quad per exposed voxel face. Unlike GreedyMesh the
+// result has no T-junctions, so every edge is shared by exactly two faces
+// unless voxels only touch along an edge.
+func Faces(img voxel.Image) []Quad {
+	var quads []Quad
+	b := img.Bounds()
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				index := img.Get(x, y, z)
+				if index == 0 {
+					continue
+				}
+
+				p := voxel.Pt(x, y, z)
+				for _, n := range normals {
+					if q := p.Add(n); q.In(b) && img.Get(q.X, q.Y, q.Z) != 0 {
+						continue
+					}
+
+					d := 0
+					switch {
+					case n.Y != 0:
+						d = 1
+					case n.Z != 0:
+						d = 2
+					}
+
+					origin := p
+					if n.X+n.Y+n.Z > 0 {
+						*axis(&origin, d)++
+					}
+					quads = append(quads, makeQuad(origin, d, 1, 1, face{index, n.X+n.Y+n.Z < 0}))
+				}
+			}
+		}
+	}
+	return quads
+}
+
+// WriteSTL writes the voxel surface of img as a binary STL with two
+// outward-facing triangles per exposed face.
+func WriteSTL(w io.Writer, img voxel.Image, scale float64) error {
+	quads := Faces(img)
+	bw := bufio.NewWriter(w)
+
+	var header [80]byte
+	copy(header[:], "voxel")
+	if _, err := bw.Write(header[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(quads)*2)); err != nil {
+		return err
+	}
+
+	vertex := func(p voxel.Point) [3]float32 {
+		return [3]float32{float32(float64(p.X) * scale), float32(float64(p.Y) * scale), float32(float64(p.Z) * scale)}
+	}
+
+	for _, q := range quads {
+		n := [3]float32{float32(q.Normal.X), float32(q.Normal.Y), float32(q.Normal.Z)}
+		c := q.Corners
+		tris := [2]stlTriangle{
+			{n, [3][3]float32{vertex(c[0]), vertex(c[1]), vertex(c[2])}, 0},
+			{n, [3][3]float32{vertex(c[0]), vertex(c[2]), vertex(c[3])}, 0},
+		}
+		if err := binary.Write(bw, binary.LittleEndian, &tris); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
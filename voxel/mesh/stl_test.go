@@ -0,0 +1,51 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestWriteSTL(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 3, 3, 3))
+	img.Set(0, 0, 0, 1)
+	img.Set(1, 0, 0, 1)
+	img.Set(2, 2, 2, 1)
+
+	var buf bytes.Buffer
+	if err := WriteSTL(&buf, img, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two adjacent voxels expose 10 faces and the lone voxel 6.
+	const faces = 16
+
+	data := buf.Bytes()
+	if n := binary.LittleEndian.Uint32(data[80:]); n != 2*faces {
+		t.Errorf("got %d triangles, expected %d", n, 2*faces)
+	}
+	if n := len(data); n != 84+2*faces*50 {
+		t.Errorf("got %d bytes, expected %d", n, 84+2*faces*50)
+	}
+
+	// Edges of a closed surface are shared by exactly two triangles, once in
+	// each direction.
+	edges := make(map[[2]voxel.Point]int)
+	for _, q := range Faces(img) {
+		for i := range q.Corners {
+			edges[[2]voxel.Point{q.Corners[i], q.Corners[(i+1)%4]}]++
+		}
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]voxel.Point{e[1], e[0]}] != 1 {
+			t.Fatalf("edge %v is not manifold", e)
+		}
+	}
+}
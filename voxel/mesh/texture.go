@@ -0,0 +1,34 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"image/color"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+// IndexTexture returns the palette indices of p and its width, height and
+// depth, laid out with x varying fastest and then y, ready to be uploaded as
+// an R8 3D texture. The data is shared with p, not copied.
+func IndexTexture(p *voxel.Paletted) ([]byte, int, int, int) {
+	b := p.Bounds()
+	return p.Data, b.Dx(), b.Dy(), b.Dz()
+}
+
+// PaletteLUT returns pal as a 256×1 RGBA8 texture for looking up the colors
+// of an IndexTexture. As with Paletted.RGBA, index 0 and the indices past
+// the end of pal are fully transparent.
+func PaletteLUT(pal color.Palette) []byte {
+	lut := make([]byte, 4*256)
+	for i, c := range pal {
+		if i == 0 || i >= 256 {
+			continue
+		}
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		copy(lut[4*i:], []byte{rgba.R, rgba.G, rgba.B, rgba.A})
+	}
+	return lut
+}
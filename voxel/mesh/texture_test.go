@@ -0,0 +1,44 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"bytes"
+	"image/color"
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestIndexTexture(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 4, 3, 2))
+	img.Set(1, 0, 0, 5)
+	img.Set(3, 2, 1, 9)
+
+	data, w, h, d := IndexTexture(img)
+	if w != 4 || h != 3 || d != 2 || len(data) != w*h*d {
+		t.Fatalf("got %d bytes for %dx%dx%d", len(data), w, h, d)
+	}
+	if data[1] != 5 || data[(1*h+2)*w+3] != 9 {
+		t.Error("indices are not in x, y, z order")
+	}
+
+	lut := PaletteLUT(palette.Plan9)
+	if len(lut) != 4*256 {
+		t.Fatalf("got %d bytes of LUT, expected %d", len(lut), 4*256)
+	}
+	c := palette.Plan9[9].(color.RGBA)
+	if !bytes.Equal(lut[4*9:4*10], []byte{c.R, c.G, c.B, c.A}) {
+		t.Errorf("entry 9 is %v, expected %v", lut[4*9:4*10], c)
+	}
+	if !bytes.Equal(lut[:4], make([]byte, 4)) {
+		t.Errorf("entry 0 is %v, expected transparent", lut[:4])
+	}
+
+	if short := PaletteLUT(color.Palette{color.Black, color.White}); short[4*2+3] != 0 || short[4+3] != 255 {
+		t.Error("short palette is not padded with transparent entries")
+	}
+}
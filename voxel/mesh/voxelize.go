@@ -0,0 +1,150 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+// Triangle is a triangle given by its corners.
+type Triangle [3]voxel.Pointf
+
+func dot(a, b voxel.Pointf) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func cross(a, b voxel.Pointf) voxel.Pointf {
+	return voxel.Ptf(a.Y*b.Z-a.Z*b.Y, a.Z*b.X-a.X*b.Z, a.X*b.Y-a.Y*b.X)
+}
+
+// overlapsCell reports whether t, in grid coordinates, touches the unit
+// cell at p. It is the separating axis test of Akenine-Möller: the cell
+// axes, the triangle normal and the nine cross products of the edges with
+// the cell axes.
+func overlapsCell(t Triangle, p voxel.Point) bool {
+	const h = 0.5 + 1e-9
+	c := voxel.Ptf(float64(p.X)+0.5, float64(p.Y)+0.5, float64(p.Z)+0.5)
+	v := [3]voxel.Pointf{t[0].Sub(c), t[1].Sub(c), t[2].Sub(c)}
+	edges := [3]voxel.Pointf{v[1].Sub(v[0]), v[2].Sub(v[1]), v[0].Sub(v[2])}
+	units := [3]voxel.Pointf{voxel.Ptf(1, 0, 0), voxel.Ptf(0, 1, 0), voxel.Ptf(0, 0, 1)}
+
+	separated := func(a voxel.Pointf) bool {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, vk := range v {
+			d := dot(vk, a)
+			lo, hi = math.Min(lo, d), math.Max(hi, d)
+		}
+		r := h * (math.Abs(a.X) + math.Abs(a.Y) + math.Abs(a.Z))
+		return lo > r || hi < -r
+	}
+
+	for _, u := range units {
+		if separated(u) {
+			return false
+		}
+	}
+	if separated(cross(edges[0], edges[1])) {
+		return false
+	}
+	for _, e := range edges {
+		for _, u := range units {
+			if separated(cross(e, u)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Voxelize rasterizes the surface of tris into a grid whose longest side is
+// res cells, marking every cell a triangle touches with index 1. The mesh is
+// scaled uniformly and centered in the grid; flat sides get one layer.
+func Voxelize(tris []Triangle, res int) *voxel.Paletted {
+	pal := color.Palette{color.Transparent, color.White}
+	if len(tris) == 0 || res < 1 {
+		return voxel.NewPaletted(pal, voxel.ZB)
+	}
+
+	lo, hi := tris[0][0], tris[0][0]
+	for _, t := range tris {
+		for _, v := range t {
+			lo = voxel.Ptf(math.Min(lo.X, v.X), math.Min(lo.Y, v.Y), math.Min(lo.Z, v.Z))
+			hi = voxel.Ptf(math.Max(hi.X, v.X), math.Max(hi.Y, v.Y), math.Max(hi.Z, v.Z))
+		}
+	}
+
+	extent := hi.Sub(lo)
+	scale := float64(res) / math.Max(extent.X, math.Max(extent.Y, extent.Z))
+	if math.IsInf(scale, 0) {
+		scale = 1
+	}
+
+	cells := func(e float64) int {
+		if n := int(math.Ceil(e*scale - 1e-9)); n > 1 {
+			return n
+		}
+		return 1
+	}
+	size := voxel.Pt(cells(extent.X), cells(extent.Y), cells(extent.Z))
+	img := voxel.NewPaletted(pal, voxel.Bx(0, 0, 0, size.X, size.Y, size.Z))
+	b := img.Bounds()
+
+	offset := voxel.Ptf(float64(size.X), float64(size.Y), float64(size.Z)).Sub(extent.Mul(scale)).Mul(0.5)
+	for _, t := range tris {
+		var g Triangle
+		for i, v := range t {
+			g[i] = v.Sub(lo).Mul(scale).Add(offset)
+		}
+
+		tlo := voxel.Ptf(math.Min(g[0].X, math.Min(g[1].X, g[2].X)), math.Min(g[0].Y, math.Min(g[1].Y, g[2].Y)), math.Min(g[0].Z, math.Min(g[1].Z, g[2].Z)))
+		thi := voxel.Ptf(math.Max(g[0].X, math.Max(g[1].X, g[2].X)), math.Max(g[0].Y, math.Max(g[1].Y, g[2].Y)), math.Max(g[0].Z, math.Max(g[1].Z, g[2].Z)))
+		// Widen by a cell so faces lying on a cell boundary reach the cells
+		// on both sides, including the last cell of the grid.
+		cb := voxel.Box{Min: tlo.Floor(), Max: thi.Floor()}.Expand(voxel.Pt(1, 1, 1)).Intersect(b)
+
+		for z := cb.Min.Z; z < cb.Max.Z; z++ {
+			for y := cb.Min.Y; y < cb.Max.Y; y++ {
+				for x := cb.Min.X; x < cb.Max.X; x++ {
+					if overlapsCell(g, voxel.Pt(x, y, z)) {
+						img.Set(x, y, z, 1)
+					}
+				}
+			}
+		}
+	}
+	return img
+}
+
+// VoxelizeSolid is like Voxelize but also fills the inside of the mesh. The
+// cells reachable from outside the grid through empty faces are flood
+// filled, and every other cell becomes solid. A mesh with holes larger than
+// a cell leaks, leaving its interior empty.
+func VoxelizeSolid(tris []Triangle, res int) *voxel.Paletted {
+	shell := Voxelize(tris, res)
+	b := shell.Bounds()
+	if b.Empty() {
+		return shell
+	}
+
+	// Pad by one cell so the outside is connected around the shell.
+	const outside = 2
+	padded := voxel.NewPaletted(shell.Palette, voxel.Box{Max: b.Max.Add(voxel.Pt(2, 2, 2))})
+	voxel.Blit(padded, shell, voxel.Pt(1, 1, 1), b)
+	voxel.FloodFill(padded, voxel.ZP, outside)
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if padded.Get(x+1, y+1, z+1) != outside {
+					shell.Set(x, y, z, 1)
+				}
+			}
+		}
+	}
+	return shell
+}
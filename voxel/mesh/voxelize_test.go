@@ -0,0 +1,103 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mesh
+
+import (
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestVoxelize(t *testing.T) {
+	tri := Triangle{voxel.Ptf(0, 0, 3), voxel.Ptf(20, 0, 3), voxel.Ptf(0, 20, 3)}
+	img := Voxelize([]Triangle{tri}, 10)
+
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 10, 10, 1) {
+		t.Fatalf("got bounds %v, expected 10x10x1", b)
+	}
+
+	var n int
+	img.EachVoxel(func(x, y, z int, index uint8) {
+		n++
+		if x+y > 10 {
+			t.Errorf("voxel %v is outside the triangle", voxel.Pt(x, y, z))
+		}
+	})
+	if n < 55 || n > 64 {
+		t.Errorf("got %d voxels, expected between 55 and 64", n)
+	}
+	for _, p := range []voxel.Point{voxel.Pt(0, 0, 0), voxel.Pt(9, 0, 0), voxel.Pt(0, 9, 0), voxel.Pt(4, 4, 0)} {
+		if img.Get(p.X, p.Y, p.Z) != 1 {
+			t.Errorf("voxel %v is not set", p)
+		}
+	}
+	if img.Get(9, 9, 0) != 0 {
+		t.Error("far corner is set")
+	}
+}
+
+func boxMesh(lo, hi voxel.Pointf) []Triangle {
+	c := func(i int) voxel.Pointf {
+		p := lo
+		if i&1 != 0 {
+			p.X = hi.X
+		}
+		if i&2 != 0 {
+			p.Y = hi.Y
+		}
+		if i&4 != 0 {
+			p.Z = hi.Z
+		}
+		return p
+	}
+
+	faces := [6][4]int{
+		{0, 2, 6, 4}, {1, 5, 7, 3},
+		{0, 4, 5, 1}, {2, 3, 7, 6},
+		{0, 1, 3, 2}, {4, 6, 7, 5},
+	}
+	var tris []Triangle
+	for _, f := range faces {
+		tris = append(tris, Triangle{c(f[0]), c(f[1]), c(f[2])}, Triangle{c(f[0]), c(f[2]), c(f[3])})
+	}
+	return tris
+}
+
+func TestVoxelizeMaxPlane(t *testing.T) {
+	// The +X, +Y and +Z faces of the box lie on the max planes of the grid.
+	img := Voxelize(boxMesh(voxel.Ptf(0, 0, 0), voxel.Ptf(8, 8, 8)), 8)
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 8, 8, 8) {
+		t.Fatalf("got bounds %v", b)
+	}
+
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			for _, p := range []voxel.Point{voxel.Pt(7, i, j), voxel.Pt(i, 7, j), voxel.Pt(i, j, 7)} {
+				if img.Get(p.X, p.Y, p.Z) != 1 {
+					t.Fatalf("voxel %v on a max face is not set", p)
+				}
+			}
+		}
+	}
+}
+
+func TestVoxelizeSolid(t *testing.T) {
+	tris := boxMesh(voxel.Ptf(0, 0, 0), voxel.Ptf(8, 8, 8))
+
+	shell := Voxelize(tris, 8)
+	if idx := shell.Get(4, 4, 4); idx != 0 {
+		t.Errorf("shell interior is %d, expected 0", idx)
+	}
+
+	img := VoxelizeSolid(tris, 8)
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 8, 8, 8) {
+		t.Fatalf("got bounds %v", b)
+	}
+	var n int
+	img.EachVoxel(func(x, y, z int, index uint8) { n++ })
+	if n != 8*8*8 {
+		t.Errorf("got %d solid voxels, expected %d", n, 8*8*8)
+	}
+}
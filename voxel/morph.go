@@ -0,0 +1,51 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// Dilate returns a copy of img grown by one layer, using a 6-connected
+// structuring element. Empty voxels next to a non-empty voxel are set to
+// index.
+func Dilate(img Image, index uint8) *Paletted {
+	b := img.Bounds()
+	dst := NewPalettedAt(paletteOf(img), b)
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if idx := img.Get(x, y, z); idx != 0 {
+					dst.Set(x, y, z, idx)
+					continue
+				}
+
+				p := Point{x, y, z}
+				for _, d := range faceNeighbors {
+					if solid(img, b, p.Add(d)) {
+						dst.Set(x, y, z, index)
+						break
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Erode returns a copy of img with the outer layer of voxels removed, using
+// a 6-connected structuring element. Voxels on the bounds are removed.
+func Erode(img Image) *Paletted {
+	b := img.Bounds()
+	dst := NewPalettedAt(paletteOf(img), b)
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if idx := img.Get(x, y, z); idx != 0 && !exposed(img, b, Point{x, y, z}) {
+					dst.Set(x, y, z, idx)
+				}
+			}
+		}
+	}
+	return dst
+}
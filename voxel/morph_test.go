@@ -0,0 +1,63 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestDilate(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 7, 7, 7))
+	fillBox(img, Bx(2, 2, 2, 5, 5, 5), 1)
+
+	d := Dilate(img, 2)
+	if b := Trim(d); b != Bx(1, 1, 1, 6, 6, 6) {
+		t.Errorf("dilated to %v", b)
+	}
+
+	// Faces grow by one layer, edges and corners stay empty.
+	if h := Histogram(d); h[1] != 27 || h[2] != 6*9 {
+		t.Errorf("got %d original and %d grown voxels", h[1], h[2])
+	}
+	if idx := d.Get(1, 1, 3); idx != 0 {
+		t.Errorf("edge voxel is %d, expected 0", idx)
+	}
+}
+
+func TestErode(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 7, 7, 7))
+	fillBox(img, Bx(1, 1, 1, 6, 6, 6), 1)
+
+	e := Erode(img)
+	if b := Trim(e); b != Bx(2, 2, 2, 5, 5, 5) {
+		t.Errorf("eroded to %v", b)
+	}
+	if h := Histogram(e); h[1] != 27 {
+		t.Errorf("got %d voxels, expected 27", h[1])
+	}
+}
+
+func TestMorphOffset(t *testing.T) {
+	b := Bx(5, 5, 5, 8, 8, 8)
+	img := NewPalettedAt(palette.Plan9, b)
+	img.Set(6, 6, 6, 3)
+
+	d := Dilate(img, 4)
+	if d.Bounds() != b {
+		t.Fatalf("dilated bounds are %v, expected %v", d.Bounds(), b)
+	}
+	if a, n := d.Get(6, 6, 6), d.Get(7, 6, 6); a != 3 || n != 4 {
+		t.Errorf("got %d and %d, expected 3 and 4", a, n)
+	}
+
+	e := Erode(d)
+	if e.Bounds() != b {
+		t.Fatalf("eroded bounds are %v, expected %v", e.Bounds(), b)
+	}
+	if idx := e.Get(6, 6, 6); idx != 3 {
+		t.Errorf("center is %d after erosion, expected 3", idx)
+	}
+}
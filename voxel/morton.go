@@ -0,0 +1,128 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "image/color"
+
+// MortonRange is the exclusive upper bound of the coordinates Morton can
+// encode.
+const MortonRange = 1 << 21
+
+func spreadBits(v uint64) uint64 {
+	v &= 0x1fffff
+	v = (v | v<<32) & 0x1f00000000ffff
+	v = (v | v<<16) & 0x1f0000ff0000ff
+	v = (v | v<<8) & 0x100f00f00f00f00f
+	v = (v | v<<4) & 0x10c30c30c30c30c3
+	v = (v | v<<2) & 0x1249249249249249
+	return v
+}
+
+func compactBits(v uint64) uint64 {
+	v &= 0x1249249249249249
+	v = (v | v>>2) & 0x10c30c30c30c30c3
+	v = (v | v>>4) & 0x100f00f00f00f00f
+	v = (v | v>>8) & 0x1f0000ff0000ff
+	v = (v | v>>16) & 0x1f00000000ffff
+	v = (v | v>>32) & 0x1fffff
+	return v
+}
+
+var mortonTable = func() (t [256]uint64) {
+	for i := range t {
+		t[i] = spreadBits(uint64(i))
+	}
+	return
+}()
+
+func spread(v int) uint64 {
+	return mortonTable[v&0xff] | mortonTable[v>>8&0xff]<<24 | mortonTable[v>>16&0x1f]<<48
+}
+
+// Morton interleaves the bits of the coordinates of p into a Z-order code,
+// with X in the lowest bit. Each coordinate must be in [0, MortonRange);
+// higher bits are discarded.
+func Morton(p Point) uint64 {
+	return spread(p.X) | spread(p.Y)<<1 | spread(p.Z)<<2
+}
+
+// UnMorton is the inverse of Morton.
+func UnMorton(code uint64) Point {
+	return Point{int(compactBits(code)), int(compactBits(code >> 1)), int(compactBits(code >> 2))}
+}
+
+// MortonPaletted is a paletted image stored in Z-order rather than row
+// major order, so that voxels close in space are close in memory. Offsets
+// are more expensive to compute than for Paletted, so it only pays off when
+// cache misses dominate; compare BenchmarkAOPaletted and
+// BenchmarkAOMortonPaletted on the target machine.
+type MortonPaletted struct {
+	bounds  Box
+	Palette color.Palette
+	Data    []uint8
+}
+
+func NewMortonPaletted(p color.Palette, b Box) *MortonPaletted {
+	img := &MortonPaletted{Palette: p}
+	img.SetBounds(b)
+	return img
+}
+
+func (p *MortonPaletted) Bounds() Box {
+	return p.bounds
+}
+
+// mortonMaxData limits the size of MortonPaletted.Data.
+const mortonMaxData = 1 << 28
+
+// SetBounds resizes p to b.Max. Data holds Morton(b.Max-(1,1,1))+1 bytes,
+// which equals the voxel count for a cube with a power of two side but can be
+// far more for other shapes: a 1×1×1024 volume needs about 600MB. SetBounds
+// panics if b has a negative coordinate, if b.Max exceeds MortonRange,
+// or if Data would exceed 256MB.
+func (p *MortonPaletted) SetBounds(b Box) {
+	for _, v := range [...]int{b.Min.X, b.Min.Y, b.Min.Z, b.Max.X, b.Max.Y, b.Max.Z} {
+		if v < 0 || v > MortonRange {
+			panic("voxel: morton bounds out of range")
+		}
+	}
+
+	p.bounds = Box{ZP, b.Max}
+	if p.bounds.Empty() {
+		p.Data = nil
+		return
+	}
+
+	size := Morton(b.Max.Sub(Pt(1, 1, 1))) + 1
+	if size > mortonMaxData {
+		panic("voxel: morton image too large")
+	}
+	p.Data = make([]uint8, size)
+}
+
+func (p *MortonPaletted) SetPalette(pal color.Palette) {
+	p.Palette = pal
+}
+
+func (p *MortonPaletted) Set(x, y, z int, index uint8) {
+	p.Data[p.Offset(x, y, z)] = index
+}
+
+func (p *MortonPaletted) Get(x, y, z int) uint8 {
+	return p.Data[p.Offset(x, y, z)]
+}
+
+// GetColor returns the palette color of the voxel at x, y, z, or
+// color.Transparent if the index is outside the palette.
+func (p *MortonPaletted) GetColor(x, y, z int) color.Color {
+	if i := int(p.Get(x, y, z)); i < len(p.Palette) {
+		return p.Palette[i]
+	}
+	return color.Transparent
+}
+
+func (p *MortonPaletted) Offset(x, y, z int) int {
+	return int(Morton(Point{x, y, z}))
+}
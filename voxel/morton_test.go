@@ -0,0 +1,134 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+func TestMorton(t *testing.T) {
+	if code := Morton(Pt(1, 0, 0)); code != 1 {
+		t.Errorf("got code %b, expected 1", code)
+	}
+	if code := Morton(Pt(3, 5, 1)); code != 0x8f {
+		t.Errorf("got code %b, expected 10001111", code)
+	}
+
+	last := Pt(MortonRange-1, MortonRange-1, MortonRange-1)
+	if code := Morton(last); code != 1<<63-1 {
+		t.Errorf("got code %x for %v", code, last)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		p := Pt(r.Intn(MortonRange), r.Intn(MortonRange), r.Intn(MortonRange))
+		if q := UnMorton(Morton(p)); q != p {
+			t.Fatalf("%v round-tripped to %v", p, q)
+		}
+	}
+}
+
+func TestMortonPaletted(t *testing.T) {
+	b := Bx(0, 0, 0, 13, 7, 30)
+	img := NewMortonPaletted(palette.Plan9, b)
+	ref := NewPaletted(palette.Plan9, b)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		x, y, z, index := r.Intn(13), r.Intn(7), r.Intn(30), uint8(r.Intn(256))
+		img.Set(x, y, z, index)
+		ref.Set(x, y, z, index)
+	}
+
+	for z := 0; z < 30; z++ {
+		for y := 0; y < 7; y++ {
+			for x := 0; x < 13; x++ {
+				if a, b := img.Get(x, y, z), ref.Get(x, y, z); a != b {
+					t.Fatalf("voxel %v is %d, expected %d", Pt(x, y, z), a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestMortonPalettedGetColor(t *testing.T) {
+	img := NewMortonPaletted(nil, Bx(0, 0, 0, 2, 2, 2))
+	img.Set(1, 1, 1, 3)
+	if c := img.GetColor(1, 1, 1); c != color.Transparent {
+		t.Errorf("got %v with no palette, expected transparent", c)
+	}
+
+	img.SetPalette(color.Palette{color.Transparent, color.White})
+	if c := img.GetColor(1, 1, 1); c != color.Transparent {
+		t.Errorf("got %v past the end of the palette, expected transparent", c)
+	}
+	img.Set(1, 1, 1, 1)
+	if c := img.GetColor(1, 1, 1); c != color.White {
+		t.Errorf("got %v, expected white", c)
+	}
+}
+
+func TestMortonPalettedBounds(t *testing.T) {
+	for _, b := range []Box{
+		Bx(-1, 0, 0, 4, 4, 4),
+		Bx(0, 0, 0, MortonRange+1, 1, 1),
+		Bx(0, 0, 0, 1, 1, 1024),
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("bounds %v accepted", b)
+				}
+			}()
+			NewMortonPaletted(nil, b)
+		}()
+	}
+
+	if n := len(NewMortonPaletted(nil, Bx(0, 0, 0, 64, 64, 64)).Data); n != 64*64*64 {
+		t.Errorf("64³ cube uses %d bytes, expected %d", n, 64*64*64)
+	}
+}
+
+func benchmarkAO(b *testing.B, img Image, each func(fn func(p Point))) {
+	DrawSphere(img, Pt(128, 128, 128), 120, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum float64
+		each(func(p Point) {
+			if img.Get(p.X, p.Y, p.Z) != 0 {
+				sum += AmbientOcclusion(img, p, FacePosZ)
+			}
+		})
+	}
+}
+
+// The AO pass visits each image in its storage order, which is how a
+// mesher walking the data would access it.
+
+func BenchmarkAOPaletted(b *testing.B) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 256, 256, 256))
+	benchmarkAO(b, img, func(fn func(p Point)) {
+		for z := 0; z < 256; z++ {
+			for y := 0; y < 256; y++ {
+				for x := 0; x < 256; x++ {
+					fn(Pt(x, y, z))
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkAOMortonPaletted(b *testing.B) {
+	img := NewMortonPaletted(palette.Plan9, Bx(0, 0, 0, 256, 256, 256))
+	benchmarkAO(b, img, func(fn func(p Point)) {
+		for code := range img.Data {
+			fn(UnMorton(uint64(code)))
+		}
+	})
+}
@@ -0,0 +1,169 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "image/color"
+
+// Histogram counts the voxels using each palette index within the image
+// bounds. Empty voxels are included and counted at index 0.
+func Histogram(img Image) [256]int {
+	var h [256]int
+	b := img.Bounds()
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				h[img.Get(x, y, z)]++
+			}
+		}
+	}
+	return h
+}
+
+// Remap rewrites every voxel index within the image bounds through mapping.
+func Remap(img Image, mapping [256]uint8) {
+	b := img.Bounds()
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				img.Set(x, y, z, mapping[img.Get(x, y, z)])
+			}
+		}
+	}
+}
+
+// MatchPalette maps each entry of from to the nearest entry of to, by
+// Euclidean distance in RGB. Index 0 is the empty voxel and always maps to 0,
+// as do entries missing from from. Other entries are only matched against
+// to[1:], so solid voxels stay solid unless to has no other entries.
+func MatchPalette(from, to color.Palette) [256]uint8 {
+	var mapping [256]uint8
+	if len(to) < 2 {
+		return mapping
+	}
+
+	solid := to[1:]
+	if len(solid) > 255 {
+		solid = solid[:255]
+	}
+	for i := 1; i < len(from) && i < len(mapping); i++ {
+		mapping[i] = uint8(nearestColor(solid, from[i]) + 1)
+	}
+	return mapping
+}
+
+func nearestColor(pal color.Palette, c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	best, bestDist := 0, uint64(1<<64-1)
+
+	for i, pc := range pal {
+		if i > 255 {
+			break
+		}
+
+		pr, pg, pb, _ := pc.RGBA()
+		dr, dg, db := int64(r>>8)-int64(pr>>8), int64(g>>8)-int64(pg>>8), int64(b>>8)-int64(pb>>8)
+		if dist := uint64(dr*dr + dg*dg + db*db); dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func samePalette(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CopyInto copies all of src into dst with src's origin at at, clipped to
+// dst. If the palettes differ, the indices are remapped to the nearest
+// colors of dst's palette with MatchPalette.
+func CopyInto(dst, src *Paletted, at Point) {
+	sr := src.Bounds()
+	if samePalette(dst.Palette, src.Palette) {
+		Blit(dst, src, at, sr)
+		return
+	}
+
+	mapping := MatchPalette(src.Palette, dst.Palette)
+	BlitOp(dst, src, at, sr, func(dst, src Image, dx, dy, dz, sx, sy, sz int) {
+		dst.Set(dx, dy, dz, mapping[src.Get(sx, sy, sz)])
+	})
+}
+
+// CompactPalette drops the palette entries no voxel uses and remaps the
+// voxels to the remaining entries, which keep their order. Index 0 stays
+// the empty voxel. Used indices past the end of the palette get
+// color.Transparent.
+func CompactPalette(p *Paletted) {
+	h := Histogram(p)
+	at := func(i int) color.Color {
+		if i < len(p.Palette) {
+			return p.Palette[i]
+		}
+		return color.Transparent
+	}
+
+	var mapping [256]uint8
+	pal := color.Palette{at(0)}
+	for i := 1; i < len(h); i++ {
+		if h[i] != 0 {
+			mapping[i] = uint8(len(pal))
+			pal = append(pal, at(i))
+		}
+	}
+
+	for i, index := range p.Data {
+		p.Data[i] = mapping[index]
+	}
+	p.Palette = pal
+}
+
+// Gradient returns a palette of steps colors running from from to to. The
+// 8-bit RGBA components are interpolated linearly as stored, without gamma
+// correction. Steps is clamped to 256, and a single step gives from alone.
+func Gradient(from, to color.Color, steps int) color.Palette {
+	if steps > 256 {
+		steps = 256
+	}
+	if steps <= 0 {
+		return nil
+	}
+
+	a := color.RGBAModel.Convert(from).(color.RGBA)
+	b := color.RGBAModel.Convert(to).(color.RGBA)
+	lerp := func(x, y uint8, i int) uint8 {
+		if steps == 1 {
+			return x
+		}
+		d := steps - 1
+		return uint8((int(x)*(d-i) + int(y)*i + d/2) / d)
+	}
+
+	pal := make(color.Palette, steps)
+	for i := range pal {
+		pal[i] = color.RGBA{lerp(a.R, b.R, i), lerp(a.G, b.G, i), lerp(a.B, b.B, i), lerp(a.A, b.A, i)}
+	}
+	return pal
+}
+
+// Recolor replaces the palette of p with one built by passing every entry
+// through fn. The voxel indices are unchanged. A new palette is allocated,
+// so other images sharing the old one are not affected.
+func Recolor(p *Paletted, fn func(index uint8, c color.Color) color.Color) {
+	pal := make(color.Palette, len(p.Palette))
+	for i, c := range p.Palette {
+		pal[i] = fn(uint8(i), c)
+	}
+	p.Palette = pal
+}
@@ -0,0 +1,154 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color"
+	"image/color/palette"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	fillBox(img, Bx(0, 0, 0, 2, 2, 2), 3)
+	fillBox(img, Bx(0, 0, 3, 4, 4, 4), 200)
+
+	h := Histogram(img)
+	if h[3] != 8 || h[200] != 16 || h[0] != 64-8-16 {
+		t.Errorf("unexpected counts: 0=%d 3=%d 200=%d", h[0], h[3], h[200])
+	}
+}
+
+func TestRemap(t *testing.T) {
+	from := color.Palette{
+		color.Transparent,
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+	}
+	to := color.Palette{
+		color.Transparent,
+		color.RGBA{0, 0, 250, 255},
+		color.RGBA{250, 10, 0, 255},
+	}
+
+	mapping := MatchPalette(from, to)
+	if mapping[0] != 0 || mapping[1] != 2 || mapping[2] != 1 {
+		t.Fatalf("unexpected mapping %v", mapping[:3])
+	}
+
+	img := NewPaletted(from, Bx(0, 0, 0, 2, 1, 1))
+	img.Set(0, 0, 0, 1)
+	img.Set(1, 0, 0, 2)
+	Remap(img, mapping)
+
+	if a, b := img.Get(0, 0, 0), img.Get(1, 0, 0); a != 2 || b != 1 {
+		t.Errorf("remapped to %d and %d, expected 2 and 1", a, b)
+	}
+}
+
+func TestMatchPaletteNearBlack(t *testing.T) {
+	// Transparent black is closer to the voxel color than any solid entry.
+	from := color.Palette{color.Transparent, color.RGBA{10, 10, 10, 255}}
+	to := color.Palette{color.Transparent, color.RGBA{128, 128, 128, 255}, color.White}
+
+	img := NewPaletted(from, Bx(0, 0, 0, 1, 1, 1))
+	img.Set(0, 0, 0, 1)
+	Remap(img, MatchPalette(from, to))
+	if idx := img.Get(0, 0, 0); idx != 1 {
+		t.Errorf("near-black voxel remapped to %d, expected 1", idx)
+	}
+}
+
+func TestCopyInto(t *testing.T) {
+	src := NewPaletted(palette.Plan9, Bx(0, 0, 0, 2, 2, 2))
+	src.Set(0, 0, 0, 10)
+	src.Set(1, 1, 1, 200)
+
+	dst := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	CopyInto(dst, src, Pt(2, 1, 0))
+	if dst.Get(2, 1, 0) != 10 || dst.Get(3, 2, 1) != 200 {
+		t.Errorf("got %d and %d, expected 10 and 200", dst.Get(2, 1, 0), dst.Get(3, 2, 1))
+	}
+
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src = NewPaletted(color.Palette{color.Transparent, red, blue}, Bx(0, 0, 0, 2, 1, 1))
+	src.Set(0, 0, 0, 1)
+	src.Set(1, 0, 0, 2)
+
+	dst = NewPaletted(color.Palette{color.Transparent, blue, red}, Bx(0, 0, 0, 4, 4, 4))
+	CopyInto(dst, src, Pt(3, 3, 3))
+	if idx := dst.Get(3, 3, 3); idx != 2 {
+		t.Errorf("red remapped to %d, expected 2", idx)
+	}
+	if h := Histogram(dst); h[0] != 63 {
+		t.Errorf("%d voxels were written, expected 1", 64-h[0])
+	}
+}
+
+func TestCompactPalette(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	img.Set(0, 0, 0, 50)
+	fillBox(img, Bx(1, 1, 1, 3, 3, 3), 7)
+	img.Set(3, 3, 3, 200)
+
+	CompactPalette(img)
+	if len(img.Palette) != 4 {
+		t.Fatalf("got %d colors, expected 4", len(img.Palette))
+	}
+
+	expected := map[Point]color.Color{
+		Pt(0, 0, 0): palette.Plan9[50],
+		Pt(1, 2, 1): palette.Plan9[7],
+		Pt(3, 3, 3): palette.Plan9[200],
+		Pt(3, 0, 0): palette.Plan9[0],
+	}
+	for p, c := range expected {
+		if got := img.GetColor(p.X, p.Y, p.Z); got != c {
+			t.Errorf("voxel %v has color %v, expected %v", p, got, c)
+		}
+	}
+	if img.Get(1, 1, 1) != 1 || img.Get(0, 0, 0) != 2 || img.Get(3, 3, 3) != 3 {
+		t.Error("indices are not in palette order")
+	}
+}
+
+func TestGradient(t *testing.T) {
+	from, to := color.RGBA{10, 200, 0, 255}, color.RGBA{30, 100, 255, 255}
+	pal := Gradient(from, to, 5)
+	if len(pal) != 5 {
+		t.Fatalf("got %d colors, expected 5", len(pal))
+	}
+	if pal[0] != from || pal[4] != to {
+		t.Errorf("runs from %v to %v, expected %v to %v", pal[0], pal[4], from, to)
+	}
+	if mid := (color.RGBA{20, 150, 128, 255}); pal[2] != mid {
+		t.Errorf("midpoint is %v, expected %v", pal[2], mid)
+	}
+	if n := len(Gradient(from, to, 1000)); n != 256 {
+		t.Errorf("got %d colors, expected 256", n)
+	}
+}
+
+func TestRecolor(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 2, 2, 2))
+	img.Set(1, 1, 1, 100)
+
+	Recolor(img, func(index uint8, c color.Color) color.Color {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		return color.RGBA{rgba.R / 2, rgba.G / 2, rgba.B / 2, rgba.A}
+	})
+
+	orig := palette.Plan9[100].(color.RGBA)
+	expected := color.RGBA{orig.R / 2, orig.G / 2, orig.B / 2, orig.A}
+	if c := img.GetColor(1, 1, 1); c != expected {
+		t.Errorf("got %v, expected %v", c, expected)
+	}
+	if idx := img.Get(1, 1, 1); idx != 100 {
+		t.Errorf("index changed to %d", idx)
+	}
+	if palette.Plan9[100] != orig {
+		t.Error("shared palette was modified")
+	}
+}
@@ -0,0 +1,232 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color"
+	"sort"
+)
+
+type colorCount struct {
+	c     color.RGBA
+	count int
+}
+
+type colorBox []colorCount
+
+func channel(c color.RGBA, ch int) uint8 {
+	switch ch {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+// widest returns the channel with the largest range in b, and that range.
+func (b colorBox) widest() (int, int) {
+	best, bestRange := 0, -1
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := 255, 0
+		for _, cc := range b {
+			v := int(channel(cc.c, ch))
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > bestRange {
+			best, bestRange = ch, hi-lo
+		}
+	}
+	return best, bestRange
+}
+
+func (b colorBox) average() color.RGBA {
+	var r, g, bl, n int
+	for _, cc := range b {
+		r += int(cc.c.R) * cc.count
+		g += int(cc.c.G) * cc.count
+		bl += int(cc.c.B) * cc.count
+		n += cc.count
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 255}
+}
+
+// medianCut builds a palette of at most n entries for the opaque colors,
+// with a transparent entry 0, and maps each color to its entry.
+func medianCut(colors []color.RGBA, n int) (color.Palette, map[color.RGBA]uint8) {
+	if n < 2 {
+		n = 2
+	} else if n > 256 {
+		n = 256
+	}
+
+	counts := make(map[color.RGBA]int)
+	for _, c := range colors {
+		if c.A != 0 {
+			c.A = 255
+			counts[c]++
+		}
+	}
+
+	var all colorBox
+	for c, count := range counts {
+		all = append(all, colorCount{c, count})
+	}
+	// Map iteration is random, so sort to keep the result deterministic.
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].c, all[j].c
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+
+	var boxes []colorBox
+	if len(all) > 0 {
+		boxes = append(boxes, all)
+	}
+
+	for len(boxes) < n-1 {
+		split, ch, widest := -1, 0, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			if c, r := b.widest(); r > widest {
+				split, ch, widest = i, c, r
+			}
+		}
+		if split < 0 {
+			break
+		}
+
+		b := boxes[split]
+		sort.SliceStable(b, func(i, j int) bool {
+			return channel(b[i].c, ch) < channel(b[j].c, ch)
+		})
+
+		var total, acc int
+		for _, cc := range b {
+			total += cc.count
+		}
+		m := 1
+		for i, cc := range b[:len(b)-1] {
+			acc += cc.count
+			if acc*2 >= total {
+				m = i + 1
+				break
+			}
+		}
+
+		boxes[split] = b[:m]
+		boxes = append(boxes, b[m:])
+	}
+
+	pal := color.Palette{color.RGBA{}}
+	lookup := make(map[color.RGBA]uint8, len(counts))
+	for i, b := range boxes {
+		pal = append(pal, b.average())
+		for _, cc := range b {
+			lookup[cc.c] = uint8(i + 1)
+		}
+	}
+	return pal, lookup
+}
+
+// Quantize builds a palette of at most n entries from colors by median cut,
+// and returns the palette index of every color. Index 0 is reserved for
+// empty voxels: it is transparent, and colors with zero alpha map to it.
+// Other colors are treated as opaque. n is clamped to [2, 256].
+func Quantize(colors []color.RGBA, n int) (color.Palette, []uint8) {
+	pal, lookup := medianCut(colors, n)
+	indices := make([]uint8, len(colors))
+	for i, c := range colors {
+		if c.A != 0 {
+			c.A = 255
+			indices[i] = lookup[c]
+		}
+	}
+	return pal, indices
+}
+
+// ditherSteps spreads the quantization error of a voxel over the neighbors
+// that come later in z, y, x scan order, in sixteenths.
+var ditherSteps = [...]struct {
+	d Point
+	w float32
+}{
+	{Point{1, 0, 0}, 6},
+	{Point{-1, 1, 0}, 2},
+	{Point{0, 1, 0}, 3},
+	{Point{1, 1, 0}, 1},
+	{Point{0, 0, 1}, 4},
+}
+
+// QuantizeDither is like Quantize but diffuses the error of each voxel to
+// its unvisited neighbors, which breaks up the banding of smooth gradients.
+// colors is laid out like Paletted.Offset over b and is visited in z, y, x
+// order. A voxel passes 6/16 of its error on along x, 2/16, 3/16 and 1/16
+// to the row below at x-1, x and x+1, and 4/16 to the next slice. Empty
+// voxels neither receive nor pass on any error.
+func QuantizeDither(colors []color.RGBA, b Box, n int) (color.Palette, []uint8) {
+	pal, _ := medianCut(colors, n)
+	indices := make([]uint8, len(colors))
+	w, h, d := b.Dx(), b.Dy(), b.Dz()
+	errs := make([][3]float32, len(colors))
+
+	clamp := func(v float32) uint8 {
+		if v < 0 {
+			return 0
+		}
+		if v > 255 {
+			return 255
+		}
+		return uint8(v + 0.5)
+	}
+
+	for z := 0; z < d; z++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				i := z*w*h + y*w + x
+				c := colors[i]
+				if c.A == 0 {
+					continue
+				}
+
+				e := errs[i]
+				want := [3]float32{float32(c.R) + e[0], float32(c.G) + e[1], float32(c.B) + e[2]}
+				target := color.RGBA{clamp(want[0]), clamp(want[1]), clamp(want[2]), 255}
+				index := nearestColor(pal[1:], target) + 1
+				indices[i] = uint8(index)
+
+				got := pal[index].(color.RGBA)
+				diff := [3]float32{want[0] - float32(got.R), want[1] - float32(got.G), want[2] - float32(got.B)}
+				for _, s := range ditherSteps {
+					p := Point{x, y, z}.Add(s.d)
+					if p.X < 0 || p.X >= w || p.Y >= h || p.Z >= d {
+						continue
+					}
+					j := p.Z*w*h + p.Y*w + p.X
+					if colors[j].A == 0 {
+						continue
+					}
+					for k := range diff {
+						errs[j][k] += diff[k] * s.w / 16
+					}
+				}
+			}
+		}
+	}
+	return pal, indices
+}
@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	const size = 16
+	colors := make([]color.RGBA, size*size*size)
+	for z := 0; z < size; z++ {
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				colors[z*size*size+y*size+x] = color.RGBA{uint8(x * 16), uint8(y * 16), uint8(z * 16), 255}
+			}
+		}
+	}
+	colors[0] = color.RGBA{}
+
+	for _, n := range []int{2, 16, 64, 256} {
+		pal, indices := Quantize(colors, n)
+		if len(pal) > n {
+			t.Errorf("got %d colors, expected at most %d", len(pal), n)
+		}
+		if pal[0] != (color.RGBA{}) || indices[0] != 0 {
+			t.Errorf("transparent voxel maps to %d with color %v", indices[0], pal[0])
+		}
+
+		for i, index := range indices[1:] {
+			if index == 0 || int(index) >= len(pal) {
+				t.Fatalf("voxel %d has index %d, palette has %d entries", i+1, index, len(pal))
+			}
+		}
+	}
+
+	// Few distinct colors are kept exactly.
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	pal, indices := Quantize([]color.RGBA{red, blue, red}, 8)
+	if len(pal) != 3 || pal[indices[0]] != red || pal[indices[1]] != blue || indices[2] != indices[0] {
+		t.Errorf("unexpected palette %v with indices %v", pal, indices)
+	}
+}
+
+func TestQuantizeDither(t *testing.T) {
+	b := Bx(0, 0, 0, 32, 8, 8)
+	colors := make([]color.RGBA, b.Dx()*b.Dy()*b.Dz())
+	for i := range colors {
+		v := uint8(i % 32 * 8)
+		colors[i] = color.RGBA{v, v, v, 255}
+	}
+	colors[5] = color.RGBA{}
+
+	pal, flat := Quantize(colors, 4)
+	dpal, dithered := QuantizeDither(colors, b, 4)
+	if len(dpal) != len(pal) {
+		t.Fatalf("got %d colors, expected %d", len(dpal), len(pal))
+	}
+	if dithered[5] != 0 {
+		t.Errorf("empty voxel got index %d", dithered[5])
+	}
+
+	var fh, dh [256]int
+	for i := range flat {
+		fh[flat[i]]++
+		dh[dithered[i]]++
+		if dithered[i] == 0 && i != 5 || int(dithered[i]) >= len(dpal) {
+			t.Fatalf("voxel %d has index %d", i, dithered[i])
+		}
+	}
+	if fh == dh {
+		t.Error("dithering did not change the index distribution")
+	}
+
+	// Dithering keeps the average brightness of a run closer to the input.
+	mean := func(pal color.Palette, indices []uint8) float64 {
+		var sum float64
+		for _, i := range indices[32*3+8 : 32*3+24] {
+			sum += float64(pal[i].(color.RGBA).R)
+		}
+		return sum / 16
+	}
+	var want float64
+	for _, c := range colors[32*3+8 : 32*3+24] {
+		want += float64(c.R) / 16
+	}
+	if df, dd := math.Abs(mean(pal, flat)-want), math.Abs(mean(dpal, dithered)-want); dd > df {
+		t.Errorf("dithered mean is off by %g, flat by %g", dd, df)
+	}
+}
@@ -0,0 +1,176 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "math"
+
+type Ray struct {
+	Origin, Dir Pointf
+}
+
+func component(p Pointf, d int) float64 {
+	switch d {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	default:
+		return p.Z
+	}
+}
+
+func coord(p Point, d int) int {
+	switch d {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	default:
+		return p.Z
+	}
+}
+
+// Raycast walks the cells along r with the Amanatides-Woo traversal and
+// returns the first non-empty voxel within maxDist of the origin, together
+// with the face the ray entered it through. A ray starting inside a
+// non-empty voxel hits it immediately with face -1.
+func Raycast(img Image, r Ray, maxDist float64) (hit Point, face int, ok bool) {
+	b := img.Bounds()
+	if b.Empty() {
+		return ZP, -1, false
+	}
+
+	l := r.Dir.Len()
+	if l == 0 {
+		p := r.Origin.Floor()
+		return p, -1, solid(img, b, p)
+	}
+
+	traverse(b, r.Origin, r.Dir.Mul(1/l), maxDist, func(p Point, f int) bool {
+		if img.Get(p.X, p.Y, p.Z) != 0 {
+			hit, face, ok = p, f, true
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return ZP, -1, false
+	}
+	return hit, face, true
+}
+
+// March calls fn for every cell of b that the segment from from to to passes
+// through, in order from from, until fn returns false.
+func (b Box) March(from, to Pointf, fn func(p Point) bool) {
+	if b.Empty() {
+		return
+	}
+
+	dir := to.Sub(from)
+	l := dir.Len()
+	if l == 0 {
+		if p := from.Floor(); p.In(b) {
+			fn(p)
+		}
+		return
+	}
+
+	traverse(b, from, dir.Mul(1/l), l, func(p Point, _ int) bool {
+		return fn(p)
+	})
+}
+
+// traverse calls fn for the cells of b along the ray from origin in the unit
+// direction dir, up to a distance of maxDist, with the face each cell was
+// entered through, until fn returns false. The first cell has face -1 unless
+// the ray starts outside b.
+func traverse(b Box, origin, dir Pointf, maxDist float64, fn func(p Point, face int) bool) {
+	// Clip the ray against the bounds.
+	tmin, tmax := 0.0, maxDist
+	face := -1
+	for d := 0; d < 3; d++ {
+		o, v := component(origin, d), component(dir, d)
+		lo, hi := float64(coord(b.Min, d)), float64(coord(b.Max, d))
+		if v == 0 {
+			if o < lo || o >= hi {
+				return
+			}
+			continue
+		}
+
+		t0, t1 := (lo-o)/v, (hi-o)/v
+		entry := 2 * d
+		if t0 > t1 {
+			t0, t1 = t1, t0
+			entry++
+		}
+		if t0 > tmin {
+			tmin, face = t0, entry
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+	}
+	if tmin > tmax {
+		return
+	}
+
+	p := origin.Add(dir.Mul(tmin)).Floor()
+	if face >= 0 {
+		// Snap the entry cell onto the bounds to absorb rounding errors.
+		d := face / 2
+		if face%2 == 0 {
+			setAxis(&p, d, coord(b.Min, d))
+		} else {
+			setAxis(&p, d, coord(b.Max, d)-1)
+		}
+	}
+
+	var (
+		step   [3]int
+		tNext  [3]float64
+		tDelta [3]float64
+	)
+	for d := 0; d < 3; d++ {
+		v := component(dir, d)
+		switch {
+		case v > 0:
+			step[d] = 1
+			tNext[d] = (float64(coord(p, d)+1) - component(origin, d)) / v
+			tDelta[d] = 1 / v
+		case v < 0:
+			step[d] = -1
+			tNext[d] = (float64(coord(p, d)) - component(origin, d)) / v
+			tDelta[d] = -1 / v
+		default:
+			tNext[d] = math.Inf(1)
+			tDelta[d] = math.Inf(1)
+		}
+	}
+
+	for p.In(b) {
+		if !fn(p, face) {
+			return
+		}
+
+		d := 0
+		if tNext[1] < tNext[d] {
+			d = 1
+		}
+		if tNext[2] < tNext[d] {
+			d = 2
+		}
+		if tNext[d] > tmax {
+			return
+		}
+
+		setAxis(&p, d, coord(p, d)+step[d])
+		tNext[d] += tDelta[d]
+		face = 2 * d
+		if step[d] < 0 {
+			face++
+		}
+	}
+}
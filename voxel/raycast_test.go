@@ -0,0 +1,78 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestRaycast(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 8, 8, 8))
+	img.Set(5, 2, 2, 1)
+	img.Set(4, 4, 4, 1)
+
+	tests := []struct {
+		ray     Ray
+		maxDist float64
+		hit     Point
+		face    int
+		ok      bool
+	}{
+		{Ray{Ptf(-3, 2.5, 2.5), Ptf(1, 0, 0)}, 100, Pt(5, 2, 2), FaceNegX, true},
+		{Ray{Ptf(7.5, 2.5, 2.5), Ptf(-1, 0, 0)}, 100, Pt(5, 2, 2), FacePosX, true},
+		{Ray{Ptf(5.5, 2.5, 20), Ptf(0, 0, -1)}, 100, Pt(5, 2, 2), FacePosZ, true},
+		{Ray{Ptf(-3, 2.5, 2.5), Ptf(1, 0, 0)}, 5, ZP, -1, false},
+		{Ray{Ptf(0.2, 0.5, 0.5), Ptf(1, 1, 1)}, 100, Pt(4, 4, 4), FaceNegX, true},
+		{Ray{Ptf(0.5, 0.2, 0.5), Ptf(1, 1, 1)}, 100, Pt(4, 4, 4), FaceNegY, true},
+		{Ray{Ptf(-1.2, -1.5, -1.5), Ptf(1, 1, 1)}, 100, Pt(4, 4, 4), FaceNegZ, true},
+		{Ray{Ptf(4.5, 4.5, 4.5), Ptf(0, 1, 0)}, 100, Pt(4, 4, 4), -1, true},
+		{Ray{Ptf(0.5, 6.5, 0.5), Ptf(1, 0, 1)}, 100, ZP, -1, false},
+		{Ray{Ptf(-1, -1, -1), Ptf(-1, 0, 0)}, 100, ZP, -1, false},
+	}
+
+	for i, tt := range tests {
+		hit, face, ok := Raycast(img, tt.ray, tt.maxDist)
+		if hit != tt.hit || face != tt.face || ok != tt.ok {
+			t.Errorf("%d: got (%v, %d, %v), expected (%v, %d, %v)", i, hit, face, ok, tt.hit, tt.face, tt.ok)
+		}
+	}
+}
+
+func TestBoxMarch(t *testing.T) {
+	from, to := Ptf(0.5, 0.5, 0.5), Ptf(3.5, 2.5, 0.5)
+	march := func(b Box) []Point {
+		var cells []Point
+		b.March(from, to, func(p Point) bool {
+			cells = append(cells, p)
+			return true
+		})
+		return cells
+	}
+
+	expected := []Point{Pt(0, 0, 0), Pt(1, 0, 0), Pt(1, 1, 0), Pt(2, 1, 0), Pt(2, 2, 0), Pt(3, 2, 0)}
+	cells := march(Bx(0, 0, 0, 5, 5, 5))
+	if len(cells) != len(expected) {
+		t.Fatalf("visited %v, expected %v", cells, expected)
+	}
+	for i, p := range expected {
+		if cells[i] != p {
+			t.Fatalf("visited %v, expected %v", cells, expected)
+		}
+	}
+
+	if cells := march(Bx(1, 0, 0, 3, 5, 1)); len(cells) != 4 || cells[0] != Pt(1, 0, 0) {
+		t.Errorf("visited %v within the clipped box", cells)
+	}
+
+	var n int
+	Bx(0, 0, 0, 5, 5, 5).March(from, to, func(p Point) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Errorf("visited %d cells after stopping, expected 2", n)
+	}
+}
@@ -0,0 +1,164 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+type colorImage interface {
+	GetColor(x, y, z int) color.Color
+}
+
+func colorAt(img Image, x, y, z int) color.Color {
+	if c, ok := img.(colorImage); ok {
+		return c.GetColor(x, y, z)
+	}
+	return color.Gray{img.Get(x, y, z)}
+}
+
+// LayerImage returns the XY slice of p at depth z, sharing the palette of p.
+// It returns nil if z is outside the bounds.
+func LayerImage(p *Paletted, z int) *image.Paletted {
+	b := p.Bounds()
+	if z < b.Min.Z || z >= b.Max.Z {
+		return nil
+	}
+
+	img := image.NewPaletted(image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y), p.Palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.SetColorIndex(x, y, p.Get(x, y, z))
+		}
+	}
+	return img
+}
+
+// RenderTop projects img down the Z axis, painting each pixel with the color
+// of the topmost non-empty voxel in its column. Images without a GetColor
+// method are rendered in grayscale by index.
+func RenderTop(img Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y))
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for z := b.Max.Z - 1; z >= b.Min.Z; z-- {
+				if img.Get(x, y, z) != 0 {
+					dst.Set(x, y, colorAt(img, x, y, z))
+					break
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// IsoOptions controls RenderIso. TileSize is the height in pixels of a voxel
+// face and is rounded up to an even number. A zero Width or Height fits the
+// output to the model.
+type IsoOptions struct {
+	Width, Height int
+	TileSize      int
+	Background    color.Color
+}
+
+var isoShade = [3]uint32{256, 204, 153}
+
+func shade(c color.Color, face int) color.RGBA {
+	r, g, b, a := c.RGBA()
+	k := isoShade[face]
+	return color.RGBA{uint8(r * k >> 16), uint8(g * k >> 16), uint8(b * k >> 16), uint8(a >> 8)}
+}
+
+// RenderIso draws img in a 2:1 isometric view, seen from the +X, +Y, +Z
+// corner with Z pointing up. Voxels are painted back to front and the top,
+// left (+Y) and right (+X) faces are shaded with decreasing brightness.
+func RenderIso(img Image, opts IsoOptions) *image.RGBA {
+	s := opts.TileSize
+	if s <= 0 {
+		s = 8
+	}
+	s += s & 1
+
+	b := img.Bounds()
+	minX, maxX := (b.Min.X-b.Max.Y+1)*s, (b.Max.X-b.Min.Y+1)*s
+	minY, maxY := (b.Min.X+b.Min.Y)*s/2-(b.Max.Z-1)*s, (b.Max.X+b.Max.Y-2)*s/2-b.Min.Z*s+2*s
+
+	w, h := opts.Width, opts.Height
+	if w <= 0 || h <= 0 {
+		w, h = maxX-minX, maxY-minY
+	}
+	if w < 0 || h < 0 {
+		w, h = 0, 0
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opts.Background != nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	ox, oy := (w-(maxX-minX))/2-minX, (h-(maxY-minY))/2-minY
+
+	var voxels []Point
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.Get(x, y, z) != 0 {
+					voxels = append(voxels, Point{x, y, z})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(voxels, func(i, j int) bool {
+		p, q := voxels[i], voxels[j]
+		return p.X+p.Y+p.Z < q.X+q.Y+q.Z
+	})
+
+	for _, p := range voxels {
+		var faces [3]color.RGBA
+		c := colorAt(img, p.X, p.Y, p.Z)
+		for i := range faces {
+			faces[i] = shade(c, i)
+		}
+
+		sx, sy := ox+(p.X-p.Y)*s, oy+(p.X+p.Y)*s/2-p.Z*s
+		for py := 0; py < 2*s; py++ {
+			for px := 0; px < 2*s; px++ {
+				if face := isoFace(2*px+1, 2*py+1, 2*s); face >= 0 {
+					dst.SetRGBA(sx+px, sy+py, faces[face])
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// isoFace classifies the point (x, y) of a voxel tile, in half-pixel units,
+// as top (0), left (1), right (2) or outside (-1). s is the face height in
+// half-pixels.
+func isoFace(x, y, s int) int {
+	dx := x - s
+	if dx < 0 {
+		dx = -dx
+	}
+
+	switch {
+	case 2*y < dx || 2*y > 2*s-dx:
+		if x < s && 2*y >= s+x && 2*y <= 3*s+x {
+			return 1
+		}
+		if x >= s && 2*y >= 3*s-x && 2*y <= 5*s-x {
+			return 2
+		}
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestLayerImage(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 3, 2))
+	img.Set(3, 2, 1, 17)
+
+	layer := LayerImage(img, 1)
+	if idx := layer.ColorIndexAt(3, 2); idx != 17 {
+		t.Errorf("pixel index is %d, expected 17", idx)
+	}
+	if c := layer.At(3, 2); c != img.GetColor(3, 2, 1) {
+		t.Errorf("pixel color is %v, expected %v", c, img.GetColor(3, 2, 1))
+	}
+	if LayerImage(img, 2) != nil {
+		t.Error("expected nil for out-of-range layer")
+	}
+}
+
+func TestRenderTop(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 2, 2, 4))
+	img.Set(1, 0, 0, 10)
+	img.Set(1, 0, 1, 20)
+	img.Set(1, 0, 2, 30)
+
+	dst := RenderTop(img)
+	if c, expected := dst.At(1, 0), color.RGBAModel.Convert(palette.Plan9[30]); c != expected {
+		t.Errorf("got color %v, expected %v", c, expected)
+	}
+	if c := dst.At(0, 1); c != (color.RGBA{}) {
+		t.Errorf("empty column has color %v", c)
+	}
+}
+
+func TestRenderIso(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 2, 2, 2))
+	fillBox(img, img.Bounds(), 200)
+
+	dst := RenderIso(img, IsoOptions{Width: 48, Height: 48, TileSize: 8, Background: color.Black})
+	golden := filepath.Join("testdata", "iso.png")
+
+	if *update {
+		fp, err := os.Create(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fp.Close()
+
+		if err := png.Encode(fp, dst); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	fp, err := os.Open(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	expected, err := png.Decode(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected.Bounds() != dst.Bounds() {
+		t.Fatalf("got size %v, expected %v", dst.Bounds(), expected.Bounds())
+	}
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 48; x++ {
+			if c, e := dst.At(x, y), color.RGBAModel.Convert(expected.At(x, y)); c != e {
+				t.Fatalf("pixel %v is %v, expected %v", image.Pt(x, y), c, e)
+			}
+		}
+	}
+
+	top, left, right := dst.At(24, 8), dst.At(12, 30), dst.At(36, 30)
+	if top == left || left == right || top == right {
+		t.Error("faces are not shaded differently")
+	}
+}
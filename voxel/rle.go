@@ -0,0 +1,43 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// RunLengthEncode compresses data as a sequence of (count, value) byte
+// pairs, with runs of at most 255 values.
+func RunLengthEncode(data []uint8) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		v, n := data[i], 1
+		for i+n < len(data) && data[i+n] == v && n < 255 {
+			n++
+		}
+		out = append(out, byte(n), v)
+		i += n
+	}
+	return out
+}
+
+// RunLengthDecode expands data produced by RunLengthEncode.
+func RunLengthDecode(data []byte) ([]uint8, error) {
+	if len(data)%2 != 0 {
+		return nil, ErrInvalidData
+	}
+
+	var size int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			return nil, ErrInvalidData
+		}
+		size += int(data[i])
+	}
+
+	out := make([]uint8, 0, size)
+	for i := 0; i < len(data); i += 2 {
+		for n := 0; n < int(data[i]); n++ {
+			out = append(out, data[i+1])
+		}
+	}
+	return out, nil
+}
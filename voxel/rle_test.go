@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzRunLength(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 0, 1, 1, 2})
+	f.Add(make([]byte, 1000))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		enc := RunLengthEncode(data)
+		dec, err := RunLengthDecode(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dec, data) {
+			t.Errorf("decoded %v, expected %v", dec, data)
+		}
+	})
+}
+
+func TestRunLength(t *testing.T) {
+	data := make([]uint8, 4096)
+	data[1000] = 7
+
+	enc := RunLengthEncode(data)
+	if len(enc) >= 100 {
+		t.Errorf("encoded %d bytes into %d", len(data), len(enc))
+	}
+
+	if _, err := RunLengthDecode([]byte{0, 1}); err != ErrInvalidData {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidData)
+	}
+}
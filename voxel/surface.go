@@ -0,0 +1,68 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "image/color"
+
+func paletteOf(img Image) color.Palette {
+	if p, ok := img.(*Paletted); ok {
+		return p.Palette
+	}
+	return nil
+}
+
+func solid(img Image, b Box, p Point) bool {
+	return p.In(b) && img.Get(p.X, p.Y, p.Z) != 0
+}
+
+func exposed(img Image, b Box, p Point) bool {
+	for _, d := range faceNeighbors {
+		if !solid(img, b, p.Add(d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Surface returns a copy of img keeping only the voxels exposed on at least
+// one face. Voxels on the bounds are always exposed.
+func Surface(img Image) *Paletted {
+	b := img.Bounds()
+	dst := NewPalettedAt(paletteOf(img), b)
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				if idx := img.Get(x, y, z); idx != 0 && exposed(img, b, p) {
+					dst.Set(x, y, z, idx)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Hollow zeroes, in place, every voxel whose six face neighbors are all
+// non-empty, leaving only the shell. Voxels on the bounds are kept.
+func Hollow(img Image) {
+	var interior []Point
+	b := img.Bounds()
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := Point{x, y, z}
+				if img.Get(x, y, z) != 0 && !exposed(img, b, p) {
+					interior = append(interior, p)
+				}
+			}
+		}
+	}
+
+	for _, p := range interior {
+		img.Set(p.X, p.Y, p.Z, 0)
+	}
+}
@@ -0,0 +1,53 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestSurface(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 3, 3, 3))
+	fillBox(img, img.Bounds(), 5)
+
+	s := Surface(img)
+	if h := Histogram(s); h[5] != 26 || h[0] != 1 {
+		t.Errorf("got %d surface voxels, expected 26", h[5])
+	}
+	if idx := s.Get(1, 1, 1); idx != 0 {
+		t.Errorf("interior voxel is %d, expected 0", idx)
+	}
+}
+
+func TestHollow(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 4, 4, 4))
+	fillBox(img, img.Bounds(), 1)
+
+	Hollow(img)
+	if h := Histogram(img); h[1] != 64-8 {
+		t.Errorf("got %d shell voxels, expected 56", h[1])
+	}
+	if b := Trim(Erode(img)); !b.Empty() {
+		t.Errorf("interior voxels left in %v", b)
+	}
+}
+
+func TestSurfaceOffset(t *testing.T) {
+	b := Bx(-2, -2, -2, 2, 2, 2)
+	img := NewPalettedAt(palette.Plan9, b)
+	fillBox(img, b, 5)
+
+	s := Surface(img)
+	if s.Bounds() != b {
+		t.Fatalf("got bounds %v, expected %v", s.Bounds(), b)
+	}
+	if idx := s.Get(-2, 0, 1); idx != 5 {
+		t.Errorf("surface voxel is %d, expected 5", idx)
+	}
+	if idx := s.Get(0, -1, 0); idx != 0 {
+		t.Errorf("interior voxel is %d, expected 0", idx)
+	}
+}
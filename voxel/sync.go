@@ -0,0 +1,79 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import "sync"
+
+type synchronized struct {
+	mu  sync.RWMutex
+	img Image
+}
+
+func (s *synchronized) Bounds() Box {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.img.Bounds()
+}
+
+func (s *synchronized) Set(x, y, z int, index uint8) {
+	s.mu.Lock()
+	s.img.Set(x, y, z, index)
+	s.mu.Unlock()
+}
+
+func (s *synchronized) Get(x, y, z int) uint8 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.img.Get(x, y, z)
+}
+
+// Synchronized wraps img so that its methods are safe to call from several
+// goroutines. Only single calls are guarded: operations made of many calls,
+// such as Blit or a Get followed by a Set, are not atomic.
+func Synchronized(img Image) Image {
+	return &synchronized{img: img}
+}
+
+type striped struct {
+	locks []sync.RWMutex
+	img   Image
+}
+
+func (s *striped) lock(z int) *sync.RWMutex {
+	i := z % len(s.locks)
+	if i < 0 {
+		i += len(s.locks)
+	}
+	return &s.locks[i]
+}
+
+func (s *striped) Bounds() Box {
+	return s.img.Bounds()
+}
+
+func (s *striped) Set(x, y, z int, index uint8) {
+	l := s.lock(z)
+	l.Lock()
+	s.img.Set(x, y, z, index)
+	l.Unlock()
+}
+
+func (s *striped) Get(x, y, z int) uint8 {
+	l := s.lock(z)
+	l.RLock()
+	defer l.RUnlock()
+	return s.img.Get(x, y, z)
+}
+
+// StripedLocked is like Synchronized but spreads the locking over stripes
+// locks picked by z, so goroutines working on different slices rarely
+// contend. img must tolerate concurrent access to different voxels, as
+// Paletted does, and its bounds must not change.
+func StripedLocked(img Image, stripes int) Image {
+	if stripes < 1 {
+		stripes = 1
+	}
+	return &striped{make([]sync.RWMutex, stripes), img}
+}
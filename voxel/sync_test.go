@@ -0,0 +1,85 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"sync"
+	"testing"
+)
+
+func testConcurrentSet(t *testing.T, img Image) {
+	const workers = 8
+	b := img.Bounds()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for z := b.Min.Z; z < b.Max.Z; z++ {
+				for y := b.Min.Y; y < b.Max.Y; y++ {
+					for x := b.Min.X + w; x < b.Max.X; x += workers {
+						img.Set(x, y, z, uint8(w+1))
+						img.Get(b.Min.X, b.Min.Y, b.Min.Z)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if idx := img.Get(x, y, z); idx != uint8(x%workers+1) {
+					t.Fatalf("voxel %v has index %d, expected %d", Pt(x, y, z), idx, x%workers+1)
+				}
+			}
+		}
+	}
+}
+
+func TestSynchronized(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 16, 16, 16))
+	testConcurrentSet(t, Synchronized(img))
+}
+
+func TestStripedLocked(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 16, 16, 16))
+	testConcurrentSet(t, StripedLocked(img, 4))
+}
+
+func benchmarkLocked(b *testing.B, img Image) {
+	const workers = 8
+	size := img.Bounds().Size()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for z := w; z < size.Z; z += workers {
+					for y := 0; y < size.Y; y++ {
+						for x := 0; x < size.X; x++ {
+							img.Set(x, y, z, uint8(w))
+						}
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkSynchronized(b *testing.B) {
+	benchmarkLocked(b, Synchronized(NewPaletted(palette.Plan9, Bx(0, 0, 0, 64, 64, 64))))
+}
+
+func BenchmarkStripedLocked(b *testing.B) {
+	benchmarkLocked(b, StripedLocked(NewPaletted(palette.Plan9, Bx(0, 0, 0, 64, 64, 64)), 16))
+}
@@ -0,0 +1,79 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+// Trim returns the smallest box enclosing all non-zero voxels, or ZB if
+// the image is empty.
+func Trim(img Image) Box {
+	b := img.Bounds()
+	t := ZB
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.Get(x, y, z) != 0 {
+					t = t.Union(Bx(x, y, z, x+1, y+1, z+1))
+				}
+			}
+		}
+	}
+	return t
+}
+
+// Crop returns a copy of src holding only the trimmed content. The copy
+// starts at the origin.
+func Crop(src *Paletted) *Paletted {
+	b := Trim(src)
+	dst := NewPaletted(src.Palette, b.Sub(b.Min))
+	Blit(dst, src, ZP, b)
+	return dst
+}
+
+// Centroid returns the average position of the non-zero voxels, rounded
+// down, or ZP if the image is empty. Positions are summed relative to the
+// bounds minimum in uint64, which cannot overflow for any image that fits
+// in memory.
+func Centroid(img Image) Point {
+	b := img.Bounds()
+	var sx, sy, sz, n uint64
+
+	for z := b.Min.Z; z < b.Max.Z; z++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if img.Get(x, y, z) != 0 {
+					sx += uint64(x - b.Min.X)
+					sy += uint64(y - b.Min.Y)
+					sz += uint64(z - b.Min.Z)
+					n++
+				}
+			}
+		}
+	}
+
+	if n == 0 {
+		return ZP
+	}
+	return b.Min.Add(Point{int(sx / n), int(sy / n), int(sz / n)})
+}
+
+// Center returns a copy of src with the same bounds and the trimmed content
+// moved to their middle, rounding towards the minimum.
+func Center(src *Paletted) *Paletted {
+	b, t := src.Bounds(), Trim(src)
+	dst := NewPalettedAt(src.Palette, b)
+	at := b.Min.Add(b.Size().Sub(t.Size()).Div(2))
+	Blit(dst, src, at, t)
+	return dst
+}
+
+// CenterCentroid returns a copy of src translated so that its Centroid is
+// at the origin. The voxels keep their place within the bounds, which move
+// with them.
+func CenterCentroid(src *Paletted) *Paletted {
+	b := src.Bounds()
+	dst := NewPalettedAt(src.Palette, b.Sub(Centroid(src)))
+	copy(dst.Data, src.Data)
+	return dst
+}
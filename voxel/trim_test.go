@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package voxel
+
+import (
+	"image/color/palette"
+	"testing"
+)
+
+func TestTrim(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 10, 10, 10))
+	if b := Trim(img); b != ZB {
+		t.Errorf("empty image trimmed to %v", b)
+	}
+
+	img.Set(5, 5, 5, 7)
+	if b := Trim(img); b != Bx(5, 5, 5, 6, 6, 6) {
+		t.Errorf("trimmed to %v", b)
+	}
+
+	img.Set(2, 8, 6, 3)
+	c := Crop(img)
+	if b := c.Bounds(); b != Bx(0, 0, 0, 4, 4, 2) {
+		t.Errorf("cropped to %v", b)
+	}
+	if idx := c.Get(3, 0, 0); idx != 7 {
+		t.Errorf("cropped voxel is %d, expected 7", idx)
+	}
+	if idx := c.Get(0, 3, 1); idx != 3 {
+		t.Errorf("cropped voxel is %d, expected 3", idx)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	img := NewPalettedAt(palette.Plan9, Bx(-10, 0, 0, 10, 10, 10))
+	if c := Centroid(img); c != ZP {
+		t.Errorf("empty image has centroid %v", c)
+	}
+
+	DrawBox(img, Bx(-5, 2, 3, 0, 7, 8), 4, false)
+	if c := Centroid(img); c != Pt(-3, 4, 5) {
+		t.Errorf("got %v, expected %v", c, Pt(-3, 4, 5))
+	}
+}
+
+func TestCenter(t *testing.T) {
+	img := NewPaletted(palette.Plan9, Bx(0, 0, 0, 20, 3, 3))
+	img.Set(10, 0, 0, 6)
+
+	c := Center(img)
+	if c.Bounds() != img.Bounds() {
+		t.Errorf("bounds changed to %v", c.Bounds())
+	}
+	if b := Trim(c); b != Bx(9, 1, 1, 10, 2, 2) {
+		t.Errorf("content moved to %v, expected the middle", b)
+	}
+
+	o := CenterCentroid(img)
+	if b := o.Bounds(); b != Bx(-10, 0, 0, 10, 3, 3) {
+		t.Errorf("got bounds %v", b)
+	}
+	if idx := o.Get(0, 0, 0); idx != 6 {
+		t.Errorf("voxel at the origin is %d, expected 6", idx)
+	}
+}
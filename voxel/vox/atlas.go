@@ -0,0 +1,150 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+// atlasKeyword names the tEXt chunk that records the atlas layout.
+const atlasKeyword = "VoxelAtlas"
+
+// WriteAtlasPNG writes p as a paletted PNG with one tile per z slice, laid
+// out left to right and top to bottom in cols columns. Tiles are as large as
+// a slice, so the PNG is cols tiles wide and ceil(depth/cols) tiles high,
+// and unused tiles in the last row are empty. The palette is padded with
+// transparent entries to cover every index. The depth and cols are stored in
+// a "VoxelAtlas" tEXt chunk as "depth=D cols=C", so readers can recover the
+// layout from the header alone.
+func WriteAtlasPNG(w io.Writer, p *voxel.Paletted, cols int) error {
+	b := p.Bounds()
+	width, height, depth := b.Dx(), b.Dy(), b.Dz()
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (depth + cols - 1) / cols
+
+	pal := make(color.Palette, 256)
+	for i := range pal {
+		pal[i] = color.Transparent
+	}
+	copy(pal, p.Palette)
+
+	atlas := image.NewPaletted(image.Rect(0, 0, cols*width, rows*height), pal)
+	for z := 0; z < depth; z++ {
+		tx, ty := z%cols*width, z/cols*height
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				atlas.SetColorIndex(tx+x, ty+y, p.Get(b.Min.X+x, b.Min.Y+y, b.Min.Z+z))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, atlas); err != nil {
+		return err
+	}
+
+	// The signature and IHDR are always the first 33 bytes.
+	data := buf.Bytes()
+	text := fmt.Sprintf("%s\x00depth=%d cols=%d", atlasKeyword, depth, cols)
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	for _, b := range [][]byte{data[:33], chunk, data[33:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// atlasLayout returns the depth and cols recorded by WriteAtlasPNG in the
+// PNG data, or zeros if there are none. Only chunks before the image data
+// are searched.
+func atlasLayout(data []byte) (depth, cols int) {
+	for i := 8; i+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if n < 0 || i+12+n > len(data) || typ == "IDAT" {
+			break
+		}
+		body := data[i+8 : i+8+n]
+		if typ == "tEXt" && bytes.HasPrefix(body, []byte(atlasKeyword+"\x00")) {
+			fmt.Sscanf(string(body[len(atlasKeyword)+1:]), "depth=%d cols=%d", &depth, &cols)
+			return
+		}
+		i += 12 + n
+	}
+	return 0, 0
+}
+
+// ReadAtlasPNG reads a volume of the given depth from an atlas laid out by
+// WriteAtlasPNG with cols columns. If depth or cols is zero, both are taken
+// from the "VoxelAtlas" chunk written by WriteAtlasPNG. A paletted PNG keeps
+// its indices and palette; any other PNG is quantized to 256 colors, with
+// fully transparent pixels left empty.
+func ReadAtlasPNG(r io.Reader, depth, cols int) (*voxel.Paletted, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	atlas, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidFile.with(err)
+	}
+
+	if depth == 0 || cols == 0 {
+		depth, cols = atlasLayout(data)
+	}
+
+	if depth < 1 || cols < 1 {
+		return nil, ErrInvalidHeader
+	}
+	rows := (depth + cols - 1) / cols
+	ab := atlas.Bounds()
+	if ab.Dx()%cols != 0 || ab.Dy()%rows != 0 {
+		return nil, ErrInvalidHeader
+	}
+	width, height := ab.Dx()/cols, ab.Dy()/rows
+
+	img := voxel.NewPaletted(nil, voxel.Bx(0, 0, 0, width, height, depth))
+	if pm, ok := atlas.(*image.Paletted); ok {
+		img.Palette = pm.Palette
+		for z := 0; z < depth; z++ {
+			tx, ty := ab.Min.X+z%cols*width, ab.Min.Y+z/cols*height
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					img.Set(x, y, z, pm.ColorIndexAt(tx+x, ty+y))
+				}
+			}
+		}
+		return img, nil
+	}
+
+	colors := make([]color.RGBA, len(img.Data))
+	for z := 0; z < depth; z++ {
+		tx, ty := ab.Min.X+z%cols*width, ab.Min.Y+z/cols*height
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				colors[img.Offset(x, y, z)] = color.RGBAModel.Convert(atlas.At(tx+x, ty+y)).(color.RGBA)
+			}
+		}
+	}
+	img.Palette, img.Data = voxel.Quantize(colors, 256)
+	return img, nil
+}
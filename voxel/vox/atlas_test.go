@@ -0,0 +1,107 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestWriteAtlasPNG(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 4, 3, 5))
+	img.Set(1, 2, 0, 10)
+	img.Set(3, 0, 4, 20)
+
+	var buf bytes.Buffer
+	if err := WriteAtlasPNG(&buf, img, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	atlas, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := atlas.Bounds(); b != image.Rect(0, 0, 8, 9) {
+		t.Fatalf("atlas has bounds %v, expected 8x9", b)
+	}
+
+	pm, ok := atlas.(*image.Paletted)
+	if !ok {
+		t.Fatalf("atlas decoded as %T, expected *image.Paletted", atlas)
+	}
+	// Slice 4 is the first tile of the third row.
+	if idx := pm.ColorIndexAt(3, 6); idx != 20 {
+		t.Errorf("slice 4 has index %d at (3,0), expected 20", idx)
+	}
+	if idx := pm.ColorIndexAt(1, 2); idx != 10 {
+		t.Errorf("slice 0 has index %d at (1,2), expected 10", idx)
+	}
+	if c := pm.At(3, 6); c != palette.Plan9[20] {
+		t.Errorf("got color %v, expected %v", c, palette.Plan9[20])
+	}
+}
+
+func TestReadAtlasPNG(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 4, 3, 5))
+	for i := range img.Data {
+		img.Data[i] = uint8(i * 7)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAtlasPNG(&buf, img, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ReadAtlasPNG(bytes.NewReader(buf.Bytes()), 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds() != img.Bounds() || !bytes.Equal(out.Data, img.Data) {
+		t.Fatal("volume did not round-trip")
+	}
+
+	if depth, cols := atlasLayout(buf.Bytes()); depth != 5 || cols != 3 {
+		t.Errorf("got layout depth=%d cols=%d, expected depth=5 cols=3", depth, cols)
+	}
+	out, err = ReadAtlasPNG(bytes.NewReader(buf.Bytes()), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds() != img.Bounds() || !bytes.Equal(out.Data, img.Data) {
+		t.Fatal("volume did not round-trip from the header layout")
+	}
+	if c := out.GetColor(1, 1, 1); c != img.GetColor(1, 1, 1) {
+		t.Errorf("got color %v, expected %v", c, img.GetColor(1, 1, 1))
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 4, 6))
+	rgba.Set(1, 4, color.RGBA{255, 0, 0, 255})
+	buf.Reset()
+	png.Encode(&buf, rgba)
+
+	out, err = ReadAtlasPNG(bytes.NewReader(buf.Bytes()), 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := out.GetColor(1, 1, 1); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v, expected red", c)
+	}
+	if idx := out.Get(0, 0, 0); idx != 0 {
+		t.Errorf("transparent pixel has index %d, expected 0", idx)
+	}
+
+	if _, err := ReadAtlasPNG(bytes.NewReader(buf.Bytes()), 4, 3); err != ErrInvalidHeader {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidHeader)
+	}
+	if _, err := ReadAtlasPNG(bytes.NewReader(buf.Bytes()), 0, 0); err != ErrInvalidHeader {
+		t.Errorf("got error %v without a layout, expected %v", err, ErrInvalidHeader)
+	}
+}
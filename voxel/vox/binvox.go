@@ -0,0 +1,162 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+const (
+	binvoxMagic = "#binvox"
+
+	// binvoxMaxDim limits each dimension, keeping a hostile header from
+	// forcing a huge allocation. The binvox tool itself stops at 1024.
+	binvoxMaxDim = 1024
+)
+
+var ErrInvalidHeader = Error{"invalid header", nil}
+
+// DecodeBinvox reads a binvox file into img. Solid voxels get index 1 and
+// the default palette is used. The translate and scale header fields are
+// parsed but not applied.
+//
+// binvox stores dimensions as depth, height and width and orders voxels
+// with y running fastest, then z, then x. Following the reference reader,
+// the image spans depth along x, width along y and height along z.
+func DecodeBinvox(reader io.Reader, img Image) error {
+	r := bufio.NewReader(reader)
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return ErrInvalidFile.with(err)
+	}
+	if !strings.HasPrefix(line, binvoxMagic) {
+		return ErrInvalidFile
+	}
+
+	var (
+		dim       [3]int
+		hasDim    bool
+		translate [3]float64
+		scale     float64
+	)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return ErrInvalidHeader.with(err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "data" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "dim"):
+			if _, err := fmt.Sscanf(line, "dim %d %d %d", &dim[0], &dim[1], &dim[2]); err != nil {
+				return ErrInvalidHeader.with(err)
+			}
+			hasDim = true
+		case strings.HasPrefix(line, "translate"):
+			if _, err := fmt.Sscanf(line, "translate %g %g %g", &translate[0], &translate[1], &translate[2]); err != nil {
+				return ErrInvalidHeader.with(err)
+			}
+		case strings.HasPrefix(line, "scale"):
+			if _, err := fmt.Sscanf(line, "scale %g", &scale); err != nil {
+				return ErrInvalidHeader.with(err)
+			}
+		}
+	}
+
+	if !hasDim {
+		return ErrInvalidHeader
+	}
+	for _, d := range dim {
+		if d <= 0 || d > binvoxMaxDim {
+			return ErrInvalidHeader
+		}
+	}
+
+	depth, height, width := dim[0], dim[1], dim[2]
+	img.SetBounds(voxel.Bx(0, 0, 0, depth, width, height))
+	img.SetPalette(indexedPalette(defaultPalette[:]))
+
+	size := depth * height * width
+	for i := 0; i < size; {
+		var pair [2]byte
+		if _, err := io.ReadFull(r, pair[:]); err != nil {
+			return ErrInvalidChunk.with(err)
+		}
+
+		value, count := pair[0], int(pair[1])
+		if count == 0 || i+count > size {
+			return ErrInvalidChunk
+		}
+
+		if value != 0 {
+			for n := i; n < i+count; n++ {
+				x, z, y := n/(width*height), n/width%height, n%width
+				img.Set(x, y, z, 1)
+			}
+		}
+		i += count
+	}
+
+	return nil
+}
+
+// EncodeBinvox writes img as a binvox file, marking every non-empty voxel
+// as solid. Since most binvox tools assume cubic grids, non-cubic bounds are
+// padded with empty voxels to a cube of the largest dimension. The image is
+// written relative to its bounds minimum.
+func EncodeBinvox(writer io.Writer, img voxel.Image) error {
+	b := img.Bounds()
+	size := b.Dx()
+	if b.Dy() > size {
+		size = b.Dy()
+	}
+	if b.Dz() > size {
+		size = b.Dz()
+	}
+
+	w := bufio.NewWriter(writer)
+	fmt.Fprintf(w, "%s 1\ndim %d %d %d\ntranslate 0 0 0\nscale 1\ndata\n", binvoxMagic, size, size, size)
+
+	var (
+		value byte
+		count int
+	)
+	flush := func() {
+		if count > 0 {
+			w.Write([]byte{value, byte(count)})
+		}
+	}
+
+	for x := 0; x < size; x++ {
+		for z := 0; z < size; z++ {
+			for y := 0; y < size; y++ {
+				var v byte
+				if p := b.Min.Add(voxel.Pt(x, y, z)); p.In(b) && img.Get(p.X, p.Y, p.Z) != 0 {
+					v = 1
+				}
+
+				if v != value || count == 255 {
+					flush()
+					value, count = v, 0
+				}
+				count++
+			}
+		}
+	}
+	flush()
+
+	return w.Flush()
+}
@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"image/color"
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+var testBinvox = []byte("#binvox 1\ndim 2 2 2\ntranslate 0 0 0\nscale 1\ndata\n" +
+	"\x00\x03\x01\x01\x00\x04")
+
+func TestDecodeBinvox(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.ZB)
+	if err := DecodeBinvox(bytes.NewReader(testBinvox), img); err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 2, 2, 2) {
+		t.Errorf("got bounds %v", b)
+	}
+
+	// The fourth voxel in binvox order is x=0, z=1, y=1.
+	for z := 0; z < 2; z++ {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 2; x++ {
+				var expected uint8
+				if x == 0 && y == 1 && z == 1 {
+					expected = 1
+				}
+				if idx := img.Get(x, y, z); idx != expected {
+					t.Errorf("voxel %v is %d, expected %d", voxel.Pt(x, y, z), idx, expected)
+				}
+			}
+		}
+	}
+
+	if c := img.GetColor(0, 1, 1); c != defaultPalette[0] {
+		t.Errorf("solid voxel has color %v, expected the editor's color 1 %v", c, defaultPalette[0])
+	}
+	if c := img.GetColor(0, 0, 0); c != (color.RGBA{}) {
+		t.Errorf("empty voxel has color %v, expected transparent", c)
+	}
+
+	if err := DecodeBinvox(bytes.NewReader(testBinvox[:len(testBinvox)-2]), img); err == nil {
+		t.Error("expected error on truncated data")
+	}
+}
+
+func TestDecodeBinvoxLimits(t *testing.T) {
+	for _, dim := range []string{"4000000 4000000 4000000", "2 1025 2", "2 0 2"} {
+		data := []byte("#binvox 1\ndim " + dim + "\ndata\n\x00\x08")
+		if err := DecodeBinvox(bytes.NewReader(data), voxel.NewPaletted(nil, voxel.ZB)); err != ErrInvalidHeader {
+			t.Errorf("got %v for dim %s, expected %v", err, dim, ErrInvalidHeader)
+		}
+	}
+}
+
+func TestEncodeBinvox(t *testing.T) {
+	img := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 3, 20, 2))
+	img.Set(0, 0, 0, 5)
+	img.Set(2, 19, 1, 6)
+	img.Set(1, 7, 1, 7)
+
+	var buf bytes.Buffer
+	if err := EncodeBinvox(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := voxel.NewPaletted(palette.Plan9, voxel.ZB)
+	if err := DecodeBinvox(&buf, dec); err != nil {
+		t.Fatal(err)
+	}
+
+	if b := dec.Bounds(); b != voxel.Bx(0, 0, 0, 20, 20, 20) {
+		t.Fatalf("got bounds %v", b)
+	}
+	for z := 0; z < 20; z++ {
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				var expected uint8
+				if p := voxel.Pt(x, y, z); p.In(img.Bounds()) && img.Get(x, y, z) != 0 {
+					expected = 1
+				}
+				if idx := dec.Get(x, y, z); idx != expected {
+					t.Fatalf("voxel %v is %d, expected %d", voxel.Pt(x, y, z), idx, expected)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+const (
+	goxMagic       = "GOX "
+	goxBlockSize   = 16
+	goxMaxChunk    = 1 << 28
+	goxBlockChunk  = "BL16"
+	goxLayerChunk  = "LAYR"
+	goxBlockPixels = 64
+)
+
+type goxBlock struct {
+	index int32
+	pos   voxel.Point
+}
+
+func readGoxDict(r *bytes.Reader) (map[string][]byte, error) {
+	dict := make(map[string][]byte)
+	for {
+		var n int32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		if n == 0 {
+			return dict, nil
+		}
+		if n < 0 || int64(n) > int64(r.Len()) {
+			return nil, ErrInvalidChunk
+		}
+
+		key := make([]byte, n)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		if n < 0 || int64(n) > int64(r.Len()) {
+			return nil, ErrInvalidChunk
+		}
+
+		value := make([]byte, n)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		dict[string(key)] = value
+	}
+}
+
+// DecodeGox reads a Goxel .gox file with one model per layer. Block images
+// are decoded from the BL16 chunks and placed by the LAYR chunks, and colors
+// are collected into the shared scene palette as for DecodeQB. Each model
+// image covers the blocks of its layer and is positioned at their minimum
+// corner.
+func DecodeGox(reader io.Reader) (*Scene, error) {
+	var header struct {
+		Magic   [4]byte
+		Version int32
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, ErrInvalidFile.with(err)
+	}
+	if string(header.Magic[:]) != goxMagic {
+		return nil, ErrInvalidFile
+	}
+	if header.Version != 2 {
+		return nil, ErrInvalidVersion
+	}
+
+	var (
+		blocks []*image.NRGBA
+		pal    = newPaletteBuilder()
+		scene  = &Scene{}
+	)
+
+	for {
+		var chunk struct {
+			Id   [4]byte
+			Size int32
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, ErrInvalidChunk.with(err)
+		}
+		if chunk.Size < 0 || chunk.Size > goxMaxChunk {
+			return nil, ErrInvalidChunk
+		}
+
+		data := make([]byte, chunk.Size+4)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		data = data[:chunk.Size]
+
+		switch string(chunk.Id[:]) {
+		case goxBlockChunk:
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, ErrInvalidChunk.with(err)
+			}
+			if b := img.Bounds(); b.Dx() != goxBlockPixels || b.Dy() != goxBlockPixels {
+				return nil, ErrInvalidChunk
+			}
+
+			block := image.NewNRGBA(image.Rect(0, 0, goxBlockPixels, goxBlockPixels))
+			for y := 0; y < goxBlockPixels; y++ {
+				for x := 0; x < goxBlockPixels; x++ {
+					block.Set(x, y, img.At(img.Bounds().Min.X+x, img.Bounds().Min.Y+y))
+				}
+			}
+			blocks = append(blocks, block)
+		case goxLayerChunk:
+			r := bytes.NewReader(data)
+
+			var numBlocks int32
+			if err := binary.Read(r, binary.LittleEndian, &numBlocks); err != nil {
+				return nil, ErrInvalidChunk.with(err)
+			}
+			if numBlocks < 0 || int64(numBlocks)*20 > int64(r.Len()) {
+				return nil, ErrInvalidChunk
+			}
+
+			var (
+				layerBlocks []goxBlock
+				bounds      voxel.Box
+			)
+			for i := int32(0); i < numBlocks; i++ {
+				var v [5]int32
+				if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+					return nil, ErrInvalidChunk.with(err)
+				}
+				if v[0] < 0 || int(v[0]) >= len(blocks) {
+					return nil, ErrInvalidChunk
+				}
+
+				p := voxel.Pt(int(v[1]), int(v[2]), int(v[3]))
+				layerBlocks = append(layerBlocks, goxBlock{v[0], p})
+				bounds = bounds.Union(voxel.Box{Min: p, Max: p.Add(voxel.Pt(goxBlockSize, goxBlockSize, goxBlockSize))})
+			}
+
+			dict, err := readGoxDict(r)
+			if err != nil {
+				return nil, err
+			}
+
+			img := voxel.NewPaletted(nil, bounds.Sub(bounds.Min))
+			for _, b := range layerBlocks {
+				block, origin := blocks[b.index], b.pos.Sub(bounds.Min)
+				for i := 0; i < goxBlockSize*goxBlockSize*goxBlockSize; i++ {
+					c := block.NRGBAAt(i%goxBlockPixels, i/goxBlockPixels)
+					if c.A == 0 {
+						continue
+					}
+
+					x, y, z := i%goxBlockSize, i/goxBlockSize%goxBlockSize, i/(goxBlockSize*goxBlockSize)
+					img.Set(origin.X+x, origin.Y+y, origin.Z+z, pal.lookup(color.RGBAModel.Convert(c).(color.RGBA)))
+				}
+			}
+
+			scene.Models = append(scene.Models, Model{
+				Name:     string(bytes.TrimRight(dict["name"], "\x00")),
+				Position: bounds.Min,
+				Image:    img,
+			})
+		}
+	}
+
+	scene.Palette = pal.palette
+	for _, m := range scene.Models {
+		m.Image.SetPalette(scene.Palette)
+	}
+	return scene, nil
+}
@@ -0,0 +1,74 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func writeGoxChunk(buf *bytes.Buffer, id string, data []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, int32(len(data)))
+	buf.Write(data)
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+}
+
+func TestDecodeGox(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+
+	block := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	block.SetNRGBA(1, 0, red)  // (1,0,0)
+	block.SetNRGBA(16, 4, red) // (0,1,1)
+
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, block); err != nil {
+		t.Fatal(err)
+	}
+
+	var layer bytes.Buffer
+	binary.Write(&layer, binary.LittleEndian, []int32{2, 0, 16, 0, 0, 0, 0, 0, 0, 0, 0})
+	binary.Write(&layer, binary.LittleEndian, int32(4))
+	layer.WriteString("name")
+	binary.Write(&layer, binary.LittleEndian, int32(5))
+	layer.WriteString("layer")
+	binary.Write(&layer, binary.LittleEndian, int32(0))
+
+	var buf bytes.Buffer
+	buf.WriteString(goxMagic)
+	binary.Write(&buf, binary.LittleEndian, int32(2))
+	writeGoxChunk(&buf, "IMG ", []byte{0, 0, 0, 0})
+	writeGoxChunk(&buf, goxBlockChunk, pngData.Bytes())
+	writeGoxChunk(&buf, goxLayerChunk, layer.Bytes())
+
+	scene, err := DecodeGox(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scene.Models) != 1 {
+		t.Fatalf("got %d models, expected 1", len(scene.Models))
+	}
+
+	m := scene.Models[0]
+	if m.Name != "layer" || m.Position != voxel.Pt(0, 0, 0) || m.Image.Bounds() != voxel.Bx(0, 0, 0, 32, 16, 16) {
+		t.Errorf("unexpected model %q at %v with bounds %v", m.Name, m.Position, m.Image.Bounds())
+	}
+
+	expected := color.RGBA{255, 0, 0, 255}
+	for _, p := range []voxel.Point{voxel.Pt(17, 0, 0), voxel.Pt(16, 1, 1), voxel.Pt(1, 0, 0), voxel.Pt(0, 1, 1)} {
+		if c := m.Image.GetColor(p.X, p.Y, p.Z); c != expected {
+			t.Errorf("voxel %v is %v, expected %v", p, c, expected)
+		}
+	}
+	if h := voxel.Histogram(m.Image); h[0] != 32*16*16-4 {
+		t.Errorf("got %d empty voxels", h[0])
+	}
+}
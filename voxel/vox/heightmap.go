@@ -0,0 +1,70 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+// FromHeightmap extrudes every pixel of img into a column of voxels rising
+// from z 0, with white reaching maxHeight and black leaving the column
+// empty. Colored pixels are read by their luminance. Voxels are colored by
+// height, spreading the palette entries after the transparent index 0 from
+// the bottom to the top of the volume.
+func FromHeightmap(img image.Image, maxHeight int, pal color.Palette) *voxel.Paletted {
+	ib := img.Bounds()
+	if maxHeight < 0 {
+		maxHeight = 0
+	}
+	p := voxel.NewPaletted(pal, voxel.Bx(0, 0, 0, ib.Dx(), ib.Dy(), maxHeight))
+
+	n := len(pal)
+	if n > 256 {
+		n = 256
+	}
+	index := func(z int) uint8 {
+		if n < 2 {
+			return 1
+		}
+		return uint8(1 + z*(n-1)/maxHeight)
+	}
+
+	for y := 0; y < ib.Dy(); y++ {
+		for x := 0; x < ib.Dx(); x++ {
+			g := color.GrayModel.Convert(img.At(ib.Min.X+x, ib.Min.Y+y)).(color.Gray).Y
+			h := (int(g)*maxHeight + 127) / 255
+			for z := 0; z < h; z++ {
+				p.Set(x, y, z, index(z))
+			}
+		}
+	}
+	return p
+}
+
+// Heightmap records the top of every XY column of p as a gray value, the
+// inverse of FromHeightmap: a column whose highest non-empty voxel is the
+// top slice is white, and empty columns are black.
+func Heightmap(p *voxel.Paletted) *image.Gray {
+	b := p.Bounds()
+	img := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	depth := b.Dz()
+	if depth == 0 {
+		return img
+	}
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			h := depth
+			for h > 0 && p.Get(b.Min.X+x, b.Min.Y+y, b.Min.Z+h-1) == 0 {
+				h--
+			}
+			img.SetGray(x, y, color.Gray{uint8((h*255 + depth/2) / depth)})
+		}
+	}
+	return img
+}
@@ -0,0 +1,73 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func columnHeight(p *voxel.Paletted, x, y int) int {
+	var h int
+	for z := 0; z < p.Bounds().Dz(); z++ {
+		if p.Get(x, y, z) != 0 {
+			h = z + 1
+		}
+	}
+	return h
+}
+
+func TestFromHeightmap(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.SetGray(0, 0, color.Gray{0})
+	img.SetGray(1, 0, color.Gray{255})
+	img.SetGray(0, 1, color.Gray{128})
+	img.SetGray(1, 1, color.Gray{64})
+
+	pal := voxel.Gradient(color.Black, color.White, 5)
+	p := FromHeightmap(img, 4, pal)
+	if b := p.Bounds(); b != voxel.Bx(0, 0, 0, 2, 2, 4) {
+		t.Fatalf("got bounds %v", b)
+	}
+
+	for _, c := range []struct{ x, y, h int }{{0, 0, 0}, {1, 0, 4}, {0, 1, 2}, {1, 1, 1}} {
+		if h := columnHeight(p, c.x, c.y); h != c.h {
+			t.Errorf("column %d,%d is %d high, expected %d", c.x, c.y, h, c.h)
+		}
+	}
+	if bottom, top := p.Get(1, 0, 0), p.Get(1, 0, 3); bottom != 1 || top != 4 {
+		t.Errorf("column runs from index %d to %d, expected 1 to 4", bottom, top)
+	}
+
+	rgb := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	rgb.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	if h := columnHeight(FromHeightmap(rgb, 4, pal), 0, 0); h != 4 {
+		t.Errorf("white RGBA pixel is %d high, expected 4", h)
+	}
+}
+
+func TestHeightmap(t *testing.T) {
+	p := voxel.NewPaletted(nil, voxel.Bx(0, 0, 0, 3, 2, 10))
+	voxel.DrawLine(p, voxel.Pt(1, 1, 0), voxel.Pt(1, 1, 4), 3)
+	p.Set(2, 0, 9, 1)
+
+	img := Heightmap(p)
+	if g := img.GrayAt(1, 1).Y; g != 128 {
+		t.Errorf("column of height 5 is %d, expected 128", g)
+	}
+	if g := img.GrayAt(2, 0).Y; g != 255 {
+		t.Errorf("full column is %d, expected 255", g)
+	}
+	if g := img.GrayAt(0, 0).Y; g != 0 {
+		t.Errorf("empty column is %d, expected 0", g)
+	}
+
+	if h := columnHeight(FromHeightmap(img, 10, nil), 1, 1); h != 5 {
+		t.Errorf("column is %d high after a round trip, expected 5", h)
+	}
+}
@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+// LayeredImage is an Image stored as one paletted layer per z, which is
+// handy for handing decoded slices to the image package.
+type LayeredImage struct {
+	Layers []*image.Paletted
+}
+
+func (img *LayeredImage) SetBounds(b voxel.Box) {
+	rect := image.Rect(0, 0, b.Dx(), b.Dy())
+	img.Layers = make([]*image.Paletted, b.Dz())
+
+	for i := range img.Layers {
+		img.Layers[i] = image.NewPaletted(rect, nil)
+	}
+}
+
+func (img *LayeredImage) SetPalette(pal color.Palette) {
+	for _, layer := range img.Layers {
+		layer.Palette = pal
+	}
+}
+
+// Set stores index at x, y in layer z. Coordinates outside the layers are
+// ignored.
+func (img *LayeredImage) Set(x, y, z int, index uint8) {
+	if z >= 0 && z < len(img.Layers) {
+		img.Layers[z].SetColorIndex(x, y, index)
+	}
+}
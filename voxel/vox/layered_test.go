@@ -0,0 +1,55 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLayeredImage(t *testing.T) {
+	data := voxFile(150, sizeChunk(3, 2, 4), voxelChunk(2, [4]byte{2, 1, 3, 9}, [4]byte{0, 0, 0, 1}))
+
+	var img LayeredImage
+	if err := Decode(bytes.NewReader(data), &img); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(img.Layers) != 4 {
+		t.Fatalf("got %d layers, expected 4", len(img.Layers))
+	}
+	if idx := img.Layers[3].ColorIndexAt(2, 1); idx != 9 {
+		t.Errorf("voxel has index %d, expected 9", idx)
+	}
+	if c := img.Layers[3].At(2, 1); c != EmptyPalette[9] {
+		t.Errorf("voxel has color %v, expected %v", c, EmptyPalette[9])
+	}
+}
+
+func ExampleLayeredImage() {
+	fp, err := os.Open("test.vox")
+	if err != nil {
+		panic(err)
+	}
+	defer fp.Close()
+
+	var img LayeredImage
+	if err := Decode(fp, &img); err != nil {
+		panic(err)
+	}
+
+	// Each layer is a regular image and can be encoded as such.
+	middle := img.Layers[len(img.Layers)/2]
+	if err := png.Encode(io.Discard, middle); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(len(img.Layers), middle.Bounds().Dx(), middle.Bounds().Dy())
+	// Output: 21 21 21
+}
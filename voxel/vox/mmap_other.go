@@ -0,0 +1,21 @@
+//go:build !unix
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the whole file, as there is no mmap on this platform.
+func mapFile(fp *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(fp, data); err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
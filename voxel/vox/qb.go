@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"encoding/binary"
+	"image/color"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+const (
+	qbCodeFlag      = 2
+	qbNextSliceFlag = 6
+	qbMaxMatrixSize = 1024
+)
+
+type qbHeader struct {
+	Version               [4]byte
+	ColorFormat           uint32
+	ZAxisOrientation      uint32
+	Compressed            uint32
+	VisibilityMaskEncoded uint32
+	NumMatrices           uint32
+}
+
+// DecodeQB reads a Qubicle .qb file with one model per matrix. Colors are
+// collected into the shared scene palette, with index 0 reserved for empty
+// voxels; files with more than 255 distinct colors map the excess to the
+// nearest collected color. Coordinates are kept as stored, with y up.
+func DecodeQB(reader io.Reader) (*Scene, error) {
+	var header qbHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, ErrInvalidFile.with(err)
+	}
+	if header.ColorFormat > 1 {
+		return nil, ErrInvalidHeader
+	}
+
+	pal := newPaletteBuilder()
+	scene := &Scene{}
+
+	readVoxel := func() ([4]byte, error) {
+		var v [4]byte
+		if _, err := io.ReadFull(reader, v[:]); err != nil {
+			return v, ErrInvalidChunk.with(err)
+		}
+		return v, nil
+	}
+
+	colorIndex := func(v [4]byte) uint8 {
+		if v[3] == 0 {
+			return 0
+		}
+
+		c := color.RGBA{v[0], v[1], v[2], v[3]}
+		if header.ColorFormat == 1 {
+			c.R, c.B = c.B, c.R
+		}
+		if header.VisibilityMaskEncoded != 0 {
+			c.A = 255
+		}
+		return pal.lookup(c)
+	}
+
+	for m := uint32(0); m < header.NumMatrices; m++ {
+		var nameLen uint8
+		if err := binary.Read(reader, binary.LittleEndian, &nameLen); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(reader, name); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+
+		var (
+			size [3]uint32
+			pos  [3]int32
+		)
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &pos); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		if size[0] > qbMaxMatrixSize || size[1] > qbMaxMatrixSize || size[2] > qbMaxMatrixSize {
+			return nil, ErrInvalidChunk
+		}
+
+		sx, sy, sz := int(size[0]), int(size[1]), int(size[2])
+		img := voxel.NewPaletted(nil, voxel.Bx(0, 0, 0, sx, sy, sz))
+
+		if header.Compressed == 0 {
+			for z := 0; z < sz; z++ {
+				for y := 0; y < sy; y++ {
+					for x := 0; x < sx; x++ {
+						v, err := readVoxel()
+						if err != nil {
+							return nil, err
+						}
+						img.Set(x, y, z, colorIndex(v))
+					}
+				}
+			}
+		} else {
+			for z := 0; z < sz; z++ {
+				for i := 0; ; {
+					v, err := readVoxel()
+					if err != nil {
+						return nil, err
+					}
+
+					flag := binary.LittleEndian.Uint32(v[:])
+					if flag == qbNextSliceFlag {
+						break
+					}
+
+					count := uint32(1)
+					if flag == qbCodeFlag {
+						if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+							return nil, ErrInvalidChunk.with(err)
+						}
+						if v, err = readVoxel(); err != nil {
+							return nil, err
+						}
+					}
+
+					if uint64(i)+uint64(count) > uint64(sx*sy) {
+						return nil, ErrInvalidChunk
+					}
+
+					index := colorIndex(v)
+					for ; count > 0; count-- {
+						img.Set(i%sx, i/sx, z, index)
+						i++
+					}
+				}
+			}
+		}
+
+		scene.Models = append(scene.Models, Model{
+			Name:     string(name),
+			Position: voxel.Pt(int(pos[0]), int(pos[1]), int(pos[2])),
+			Image:    img,
+		})
+	}
+
+	scene.Palette = pal.palette
+	for _, m := range scene.Models {
+		m.Image.SetPalette(scene.Palette)
+	}
+	return scene, nil
+}
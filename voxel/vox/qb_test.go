@@ -0,0 +1,76 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func writeQBHeader(buf *bytes.Buffer, compressed uint32) {
+	binary.Write(buf, binary.LittleEndian, qbHeader{
+		Version:     [4]byte{1, 1, 0, 0},
+		ColorFormat: 0,
+		Compressed:  compressed,
+		NumMatrices: 1,
+	})
+	buf.WriteByte(4)
+	buf.WriteString("test")
+	binary.Write(buf, binary.LittleEndian, [3]uint32{2, 2, 1})
+	binary.Write(buf, binary.LittleEndian, [3]int32{-1, 2, 3})
+}
+
+func checkQBScene(t *testing.T, scene *Scene) {
+	if len(scene.Models) != 1 {
+		t.Fatalf("got %d models, expected 1", len(scene.Models))
+	}
+
+	m := scene.Models[0]
+	if m.Name != "test" || m.Position != voxel.Pt(-1, 2, 3) || m.Image.Bounds() != voxel.Bx(0, 0, 0, 2, 2, 1) {
+		t.Errorf("unexpected model %q at %v with bounds %v", m.Name, m.Position, m.Image.Bounds())
+	}
+
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	expected := []color.Color{red, red, color.Transparent, blue}
+	for i, c := range expected {
+		x, y := i%2, i/2
+		if got := m.Image.GetColor(x, y, 0); got != c {
+			t.Errorf("voxel (%d,%d,0) is %v, expected %v", x, y, got, c)
+		}
+	}
+	if idx := m.Image.Get(0, 1, 0); idx != 0 {
+		t.Errorf("empty voxel has index %d", idx)
+	}
+}
+
+func TestDecodeQB(t *testing.T) {
+	var buf bytes.Buffer
+	writeQBHeader(&buf, 0)
+	buf.Write([]byte{255, 0, 0, 255, 255, 0, 0, 255, 0, 0, 0, 0, 0, 0, 255, 255})
+
+	scene, err := DecodeQB(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkQBScene(t, scene)
+}
+
+func TestDecodeQBCompressed(t *testing.T) {
+	var buf bytes.Buffer
+	writeQBHeader(&buf, 1)
+	binary.Write(&buf, binary.LittleEndian, []uint32{qbCodeFlag, 2})
+	buf.Write([]byte{255, 0, 0, 255, 0, 0, 0, 0, 0, 0, 255, 255})
+	binary.Write(&buf, binary.LittleEndian, uint32(qbNextSliceFlag))
+
+	scene, err := DecodeQB(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkQBScene(t, scene)
+}
@@ -0,0 +1,99 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"image/color"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+type Model struct {
+	Name     string
+	Position voxel.Point
+	Image    *voxel.Paletted
+}
+
+// Scene is a set of models placed in a shared space. All model images use
+// the scene palette.
+type Scene struct {
+	Models  []Model
+	Palette color.Palette
+}
+
+type paletteBuilder struct {
+	palette color.Palette
+	index   map[color.RGBA]uint8
+}
+
+func newPaletteBuilder() *paletteBuilder {
+	return &paletteBuilder{color.Palette{color.Transparent}, make(map[color.RGBA]uint8)}
+}
+
+func (p *paletteBuilder) lookup(c color.RGBA) uint8 {
+	if i, ok := p.index[c]; ok {
+		return i
+	}
+	if len(p.palette) < 256 {
+		i := uint8(len(p.palette))
+		p.palette = append(p.palette, c)
+		p.index[c] = i
+		return i
+	}
+
+	// The palette is full, so fall back to the nearest existing color.
+	return voxel.MatchPalette(color.Palette{color.Transparent, c}, p.palette)[1]
+}
+
+// Merge composites the models of scene into a single image covering the
+// union of their bounds, offset by each model's Position. The result is
+// shifted so the union starts at the origin. Empty voxels are transparent,
+// and later models are drawn over earlier ones.
+func Merge(scene *Scene) *voxel.Paletted {
+	var union voxel.Box
+	for _, m := range scene.Models {
+		union = union.Union(m.Image.Bounds().Add(m.Position))
+	}
+
+	img := voxel.NewPaletted(scene.Palette, union.Sub(union.Min))
+	over := func(dst, src voxel.Image, dx, dy, dz, sx, sy, sz int) {
+		if index := src.Get(sx, sy, sz); index != 0 {
+			dst.Set(dx, dy, dz, index)
+		}
+	}
+
+	for _, m := range scene.Models {
+		b := m.Image.Bounds()
+		voxel.BlitOp(img, m.Image, b.Min.Add(m.Position).Sub(union.Min), b, over)
+	}
+	return img
+}
+
+// SplitPalette builds a palette per model holding only the scene colors the
+// model uses, in scene palette order after a transparent entry 0. The
+// matching remap table maps each scene index to the model palette index,
+// ready for voxel.Remap; unused indices map to 0.
+func SplitPalette(scene *Scene) ([]color.Palette, [][256]uint8) {
+	palettes := make([]color.Palette, len(scene.Models))
+	remaps := make([][256]uint8, len(scene.Models))
+
+	for i, m := range scene.Models {
+		pal := color.Palette{color.Transparent}
+		h := voxel.Histogram(m.Image)
+		for index := 1; index < len(h); index++ {
+			if h[index] == 0 {
+				continue
+			}
+			c := color.Color(color.Transparent)
+			if index < len(scene.Palette) {
+				c = scene.Palette[index]
+			}
+			remaps[i][index] = uint8(len(pal))
+			pal = append(pal, c)
+		}
+		palettes[i] = pal
+	}
+	return palettes, remaps
+}
@@ -0,0 +1,102 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"image/color"
+	"image/color/palette"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestMerge(t *testing.T) {
+	a := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 2, 2, 2))
+	a.Set(0, 0, 0, 1)
+	a.Set(1, 1, 1, 2)
+
+	b := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 3, 2, 2))
+	b.Set(0, 0, 0, 3)
+	b.Set(2, 1, 1, 4)
+
+	scene := &Scene{
+		Models: []Model{
+			{Name: "a", Position: voxel.Pt(-1, 0, 0), Image: a},
+			{Name: "b", Position: voxel.Pt(0, 2, 1), Image: b},
+		},
+		Palette: palette.Plan9,
+	}
+
+	img := Merge(scene)
+	if size := img.Bounds().Size(); size != voxel.Pt(4, 4, 3) {
+		t.Fatalf("merged image has size %v, expected (4,4,3)", size)
+	}
+
+	expected := map[voxel.Point]uint8{
+		voxel.Pt(0, 0, 0): 1,
+		voxel.Pt(1, 1, 1): 2,
+		voxel.Pt(1, 2, 1): 3,
+		voxel.Pt(3, 3, 2): 4,
+	}
+	var n int
+	img.EachVoxel(func(x, y, z int, index uint8) {
+		n++
+		if p := voxel.Pt(x, y, z); expected[p] != index {
+			t.Errorf("voxel %v has index %d, expected %d", p, index, expected[p])
+		}
+	})
+	if n != len(expected) {
+		t.Errorf("got %d voxels, expected %d", n, len(expected))
+	}
+}
+
+func TestSplitPalette(t *testing.T) {
+	a := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 2, 2, 2))
+	a.Set(0, 0, 0, 10)
+	a.Set(1, 0, 0, 12)
+
+	b := voxel.NewPaletted(palette.Plan9, voxel.Bx(0, 0, 0, 2, 2, 2))
+	b.Set(0, 0, 0, 200)
+	b.Set(1, 1, 1, 100)
+	b.Set(1, 1, 0, 200)
+
+	scene := &Scene{Models: []Model{{Image: a}, {Image: b}}, Palette: palette.Plan9}
+	palettes, remaps := SplitPalette(scene)
+
+	expected := [][]uint8{{10, 12}, {100, 200}}
+	for i, indices := range expected {
+		pal := palettes[i]
+		if len(pal) != len(indices)+1 {
+			t.Fatalf("model %d has %d colors, expected %d", i, len(pal), len(indices)+1)
+		}
+		for j, index := range indices {
+			if pal[j+1] != palette.Plan9[index] || remaps[i][index] != uint8(j+1) {
+				t.Errorf("model %d maps index %d to %d with color %v", i, index, remaps[i][index], pal[j+1])
+			}
+		}
+	}
+
+	voxel.Remap(b, remaps[1])
+	if b.Get(0, 0, 0) != 2 || b.Get(1, 1, 1) != 1 {
+		t.Errorf("remapped to %d and %d, expected 2 and 1", b.Get(0, 0, 0), b.Get(1, 1, 1))
+	}
+}
+
+func TestPaletteBuilderFull(t *testing.T) {
+	pb := newPaletteBuilder()
+	if i := pb.lookup(color.RGBA{10, 10, 10, 255}); i != 1 {
+		t.Fatalf("first color got index %d, expected 1", i)
+	}
+	for i := 2; i < 256; i++ {
+		pb.lookup(color.RGBA{uint8(i), 255, 255, 255})
+	}
+
+	if i := pb.lookup(color.RGBA{11, 11, 11, 255}); i != 1 {
+		t.Errorf("got index %d for a color next to entry 1, expected 1", i)
+	}
+	if i := pb.lookup(color.RGBA{200, 250, 255, 255}); i != 200 {
+		t.Errorf("got index %d, expected 200", i)
+	}
+}
@@ -0,0 +1,237 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+const (
+	nbtEnd = iota
+	nbtByte
+	nbtShort
+	nbtInt
+	nbtLong
+	nbtFloat
+	nbtDouble
+	nbtByteArray
+	nbtString
+	nbtList
+	nbtCompound
+	nbtIntArray
+	nbtLongArray
+)
+
+const nbtMaxArray = 1 << 28
+
+type nbtReader struct {
+	r io.Reader
+}
+
+func (n nbtReader) read(v interface{}) error {
+	if err := binary.Read(n.r, binary.BigEndian, v); err != nil {
+		return ErrInvalidChunk.with(err)
+	}
+	return nil
+}
+
+func (n nbtReader) readString() (string, error) {
+	var l uint16
+	if err := n.read(&l); err != nil {
+		return "", err
+	}
+
+	s := make([]byte, l)
+	if _, err := io.ReadFull(n.r, s); err != nil {
+		return "", ErrInvalidChunk.with(err)
+	}
+	return string(s), nil
+}
+
+func (n nbtReader) readLength() (int, error) {
+	var l int32
+	if err := n.read(&l); err != nil {
+		return 0, err
+	}
+	if l < 0 || l > nbtMaxArray {
+		return 0, ErrInvalidChunk
+	}
+	return int(l), nil
+}
+
+// readPayload reads the payload of a tag. Byte arrays and integers are
+// returned, everything else is skipped.
+func (n nbtReader) readPayload(tag byte) (interface{}, error) {
+	switch tag {
+	case nbtByte:
+		var v int8
+		err := n.read(&v)
+		return int(v), err
+	case nbtShort:
+		var v int16
+		err := n.read(&v)
+		return int(v), err
+	case nbtInt:
+		var v int32
+		err := n.read(&v)
+		return int(v), err
+	case nbtLong, nbtDouble:
+		var v int64
+		return nil, n.read(&v)
+	case nbtFloat:
+		var v int32
+		return nil, n.read(&v)
+	case nbtByteArray:
+		l, err := n.readLength()
+		if err != nil {
+			return nil, err
+		}
+
+		v := make([]byte, l)
+		if _, err := io.ReadFull(n.r, v); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		return v, nil
+	case nbtString:
+		return n.readString()
+	case nbtList:
+		var elem byte
+		if err := n.read(&elem); err != nil {
+			return nil, err
+		}
+
+		l, err := n.readLength()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < l; i++ {
+			if _, err := n.readPayload(elem); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case nbtCompound:
+		return nil, n.readCompound(nil)
+	case nbtIntArray, nbtLongArray:
+		l, err := n.readLength()
+		if err != nil {
+			return nil, err
+		}
+
+		size := int64(l) * 4
+		if tag == nbtLongArray {
+			size *= 2
+		}
+		if _, err := io.CopyN(io.Discard, n.r, size); err != nil {
+			return nil, ErrInvalidChunk.with(err)
+		}
+		return nil, nil
+	default:
+		return nil, ErrInvalidChunk
+	}
+}
+
+// readCompound reads the named tags of a compound, passing the payloads of
+// direct children to fn if it is non-nil.
+func (n nbtReader) readCompound(fn func(name string, v interface{})) error {
+	for {
+		var tag byte
+		if err := n.read(&tag); err != nil {
+			return err
+		}
+		if tag == nbtEnd {
+			return nil
+		}
+
+		name, err := n.readString()
+		if err != nil {
+			return err
+		}
+
+		v, err := n.readPayload(tag)
+		if err != nil {
+			return err
+		}
+		if fn != nil {
+			fn(name, v)
+		}
+	}
+}
+
+// DecodeSchematic reads a gzipped MCEdit .schematic file. Block IDs are
+// mapped to palette indices through blockPalette, with unknown blocks
+// becoming empty, and the default palette is used. Minecraft's y axis is
+// up, so the schematic x, z and y axes become the image x, y and z axes.
+func DecodeSchematic(reader io.Reader, blockPalette map[int]uint8) (*voxel.Paletted, error) {
+	zr, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, ErrInvalidFile.with(err)
+	}
+	defer zr.Close()
+
+	n := nbtReader{bufio.NewReader(zr)}
+
+	var tag byte
+	if err := n.read(&tag); err != nil {
+		return nil, err
+	}
+	if tag != nbtCompound {
+		return nil, ErrInvalidFile
+	}
+	if _, err := n.readString(); err != nil {
+		return nil, err
+	}
+
+	var (
+		width, height, length int
+		blocks, addBlocks     []byte
+	)
+
+	err = n.readCompound(func(name string, v interface{}) {
+		switch name {
+		case "Width":
+			width, _ = v.(int)
+		case "Height":
+			height, _ = v.(int)
+		case "Length":
+			length, _ = v.(int)
+		case "Blocks":
+			blocks, _ = v.([]byte)
+		case "AddBlocks":
+			addBlocks, _ = v.([]byte)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	size := width * height * length
+	if width <= 0 || height <= 0 || length <= 0 || len(blocks) != size || addBlocks != nil && len(addBlocks) < (size+1)/2 {
+		return nil, ErrInvalidMainChunk
+	}
+
+	img := voxel.NewPaletted(indexedPalette(defaultPalette[:]), voxel.Bx(0, 0, 0, width, length, height))
+	for i, b := range blocks {
+		id := int(b)
+		if addBlocks != nil {
+			add := addBlocks[i>>1]
+			if i&1 == 0 {
+				add >>= 4
+			}
+			id |= int(add&0xf) << 8
+		}
+
+		if index := blockPalette[id]; index != 0 {
+			x, z, y := i%width, i/(width*length), i/width%length
+			img.Set(x, y, z, index)
+		}
+	}
+	return img, nil
+}
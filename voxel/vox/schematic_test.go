@@ -0,0 +1,80 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"image/color"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func writeNBTName(buf *bytes.Buffer, tag byte, name string) {
+	buf.WriteByte(tag)
+	binary.Write(buf, binary.BigEndian, uint16(len(name)))
+	buf.WriteString(name)
+}
+
+func TestDecodeSchematic(t *testing.T) {
+	var nbt bytes.Buffer
+	writeNBTName(&nbt, nbtCompound, "Schematic")
+	for _, dim := range []struct {
+		name  string
+		value int16
+	}{{"Width", 2}, {"Height", 3}, {"Length", 2}} {
+		writeNBTName(&nbt, nbtShort, dim.name)
+		binary.Write(&nbt, binary.BigEndian, dim.value)
+	}
+
+	writeNBTName(&nbt, nbtString, "Materials")
+	binary.Write(&nbt, binary.BigEndian, uint16(5))
+	nbt.WriteString("Alpha")
+
+	writeNBTName(&nbt, nbtList, "Entities")
+	nbt.WriteByte(nbtCompound)
+	binary.Write(&nbt, binary.BigEndian, int32(0))
+
+	// Blocks are ordered (y*Length + z)*Width + x.
+	blocks := make([]byte, 12)
+	blocks[(2*2+1)*2+0] = 1 // x=0, y=2, z=1
+	blocks[(0*2+0)*2+1] = 4 // x=1, y=0, z=0
+	blocks[(1*2+1)*2+1] = 9 // unknown
+	writeNBTName(&nbt, nbtByteArray, "Blocks")
+	binary.Write(&nbt, binary.BigEndian, int32(len(blocks)))
+	nbt.Write(blocks)
+	nbt.WriteByte(nbtEnd)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(nbt.Bytes())
+	zw.Close()
+
+	img, err := DecodeSchematic(&buf, map[int]uint8{1: 10, 4: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 2, 2, 3) {
+		t.Errorf("got bounds %v", b)
+	}
+	if idx := img.Get(0, 1, 2); idx != 10 {
+		t.Errorf("stone voxel is %d, expected 10", idx)
+	}
+	if idx := img.Get(1, 0, 0); idx != 20 {
+		t.Errorf("cobblestone voxel is %d, expected 20", idx)
+	}
+	if c := img.GetColor(0, 1, 2); c != defaultPalette[9] {
+		t.Errorf("stone voxel has color %v, expected %v", c, defaultPalette[9])
+	}
+	if c := img.Palette[0]; c != (color.RGBA{}) {
+		t.Errorf("empty index has color %v, expected transparent", c)
+	}
+	if h := voxel.Histogram(img); h[0] != 10 {
+		t.Errorf("got %d empty voxels, expected 10", h[0])
+	}
+}
@@ -0,0 +1,63 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+var ErrVoxelOutOfBounds = Error{"voxel out of bounds", nil}
+
+// Info describes a .vox file checked by Validate.
+type Info struct {
+	Models     int
+	Voxels     int
+	Size       voxel.Point // The largest extent of any model, per axis.
+	HasPalette bool
+}
+
+type validateImage struct {
+	info   Info
+	bounds voxel.Box
+	err    error
+}
+
+func (v *validateImage) visitChunk(id string) {
+	switch id {
+	case sizeShunkID:
+		v.info.Models++
+	case paletteChunkID:
+		v.info.HasPalette = true
+	}
+}
+
+func (v *validateImage) SetBounds(b voxel.Box) {
+	v.bounds = b
+	v.info.Size = v.info.Size.Max(b.Max)
+}
+
+func (v *validateImage) SetPalette(color.Palette) {}
+
+func (v *validateImage) Set(x, y, z int, index uint8) {
+	v.info.Voxels++
+	if v.err == nil && !voxel.Pt(x, y, z).In(v.bounds) {
+		v.err = ErrVoxelOutOfBounds.with(fmt.Errorf("%v outside %v", voxel.Pt(x, y, z), v.bounds))
+	}
+}
+
+// Validate parses a .vox file with the same rules as Decode but keeps no
+// voxels, so a file can be checked cheaply before it is decoded. Voxels
+// outside the size of their model are reported as ErrVoxelOutOfBounds.
+func Validate(reader io.Reader) (Info, error) {
+	var v validateImage
+	if err := Decode(reader, &v); err != nil {
+		return v.info, err
+	}
+	return v.info, v.err
+}
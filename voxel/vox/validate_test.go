@@ -0,0 +1,45 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/andreas-jonsson/voxel/voxel"
+)
+
+func TestValidate(t *testing.T) {
+	data := voxFile(150,
+		sizeChunk(4, 3, 2), voxelChunk(2, [4]byte{0, 0, 0, 1}, [4]byte{3, 2, 1, 2}),
+		sizeChunk(2, 5, 1), voxelChunk(1, [4]byte{1, 4, 0, 3}),
+		voxChunk(paletteChunkID, make([]byte, 4*256)),
+	)
+
+	info, err := Validate(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Info{Models: 2, Voxels: 3, Size: voxel.Pt(4, 5, 2), HasPalette: true}
+	if info != expected {
+		t.Errorf("got %+v, expected %+v", info, expected)
+	}
+
+	if _, err := Validate(bytes.NewReader(data[:len(data)-100])); err == nil {
+		t.Error("truncated file validated")
+	}
+
+	bad := append([]byte("VOY "), data[4:]...)
+	if _, err := Validate(bytes.NewReader(bad)); err != ErrInvalidFile {
+		t.Errorf("got %v for bad magic, expected %v", err, ErrInvalidFile)
+	}
+
+	outside := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{1, 2, 1, 1}))
+	var e Error
+	if _, err := Validate(bytes.NewReader(outside)); !errors.As(err, &e) || e.err != ErrVoxelOutOfBounds.err {
+		t.Errorf("got %v for voxel outside the model, expected %v", err, ErrVoxelOutOfBounds)
+	}
+}
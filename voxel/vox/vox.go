@@ -5,10 +5,13 @@
 package vox
 
 import (
+	"bufio"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"image/color"
 	"io"
+	"os"
 
 	"github.com/andreas-jonsson/voxel/voxel"
 )
@@ -23,6 +26,7 @@ const (
 	sizeShunkID    = "SIZE"
 	voxelChunkID   = "XYZI"
 	paletteChunkID = "RGBA"
+	indexMapID     = "IMAP"
 )
 
 var (
@@ -30,6 +34,7 @@ var (
 	ErrInvalidVersion   = Error{"invalid version", nil}
 	ErrInvalidChunk     = Error{"invalid chunk", nil}
 	ErrInvalidMainChunk = Error{"invalid main chunk", nil}
+	ErrTooManyVoxels    = Error{"too many voxels", nil}
 )
 
 type Error struct {
@@ -54,6 +59,20 @@ type Image interface {
 	Set(x, y, z int, index uint8)
 }
 
+// ProgressReporter can be implemented by an Image to follow a decode.
+// Progress is called after each chunk, and after each block of voxels in
+// large chunks, with the number of bytes of the main chunk consumed so far
+// and its total size. The last call has done equal to total.
+type ProgressReporter interface {
+	Progress(done, total uint32)
+}
+
+// chunkVisitor can be implemented by an Image to see the id of every chunk
+// in the main chunk as its header is read.
+type chunkVisitor interface {
+	visitChunk(id string)
+}
+
 type (
 	voxHeader struct {
 		Magic   [4]byte
@@ -67,7 +86,153 @@ type (
 	}
 )
 
+type DecodeOptions struct {
+	// ApplyIndexMap reorders the palette into the order shown by the editor
+	// when the file has an IMAP chunk, remapping the voxel indices to match.
+	// Index 0 stays empty, and an IMAP that is not a permutation is rejected.
+	ApplyIndexMap bool
+
+	// StrictVersion rejects files whose version is not 150.
+	StrictVersion bool
+
+	// MaxVoxels limits the total number of voxels the file may declare.
+	// Zero means no limit.
+	MaxVoxels int
+}
+
+// DefaultDecodeOptions are the options used by Decode.
+var DefaultDecodeOptions = DecodeOptions{StrictVersion: true}
+
+// voxelBlock is the number of voxels Decode reads at a time.
+const voxelBlock = 16384
+
+type voxelData struct {
+	x, y, z int
+	index   uint8
+}
+
+// heldModel is a SIZE chunk held back until the index map is known, with the
+// position in the held voxels where the model starts.
+type heldModel struct {
+	bounds voxel.Box
+	first  int
+}
+
 func Decode(reader io.Reader, img Image) error {
+	return DecodeWithOptions(reader, img, DefaultDecodeOptions)
+}
+
+type palettedImage struct {
+	*voxel.Paletted
+}
+
+func identity(x, y, z int) (int, int, int) {
+	return x, y, z
+}
+
+func (p palettedImage) SetBounds(b voxel.Box) {
+	p.Transformer = identity
+	p.Paletted.SetBounds(b)
+}
+
+func (p palettedImage) Set(x, y, z int, index uint8) {
+	if voxel.Pt(x, y, z).In(p.Bounds()) {
+		p.Data[p.Offset(x, y, z)] = index
+	}
+}
+
+// DecodeInto decodes a .vox file straight into img, replacing its bounds,
+// data and palette. The Transformer is reset to the identity, so voxels land
+// at their file coordinates, and voxels outside the declared size are
+// dropped.
+func DecodeInto(reader io.Reader, img *voxel.Paletted) error {
+	return Decode(reader, palettedImage{img})
+}
+
+type funcImage struct {
+	onSize    func(voxel.Box)
+	onPalette func(color.Palette)
+	onVoxel   func(x, y, z int, index uint8)
+}
+
+func (f funcImage) SetBounds(b voxel.Box) {
+	if f.onSize != nil {
+		f.onSize(b)
+	}
+}
+
+func (f funcImage) SetPalette(pal color.Palette) {
+	if f.onPalette != nil {
+		f.onPalette(pal)
+	}
+}
+
+func (f funcImage) Set(x, y, z int, index uint8) {
+	if f.onVoxel != nil {
+		f.onVoxel(x, y, z, index)
+	}
+}
+
+// DecodeFunc decodes a .vox file, passing the model size, the palette and
+// each voxel to the given functions as they are read. Any of them may be
+// nil.
+func DecodeFunc(reader io.Reader, onSize func(voxel.Box), onPalette func(color.Palette), onVoxel func(x, y, z int, index uint8)) error {
+	return Decode(reader, funcImage{onSize, onPalette, onVoxel})
+}
+
+func DecodeWithOptions(reader io.Reader, img Image, opts DecodeOptions) error {
+	return decode(context.Background(), reader, img, opts)
+}
+
+// contextReader makes a blocked Read return as soon as ctx is done. The
+// read itself keeps going in the background until the underlying reader
+// returns, and its result is then discarded.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+
+	// Read into a private buffer, p may be reused once we have returned.
+	buf := make([]byte, len(p))
+	done := make(chan result, 1)
+	go func() {
+		n, err := c.r.Read(buf)
+		done <- result{n, err}
+	}()
+
+	select {
+	case res := <-done:
+		return copy(p, buf[:res.n]), res.err
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	}
+}
+
+// DecodeContext is like Decode but stops when ctx is done, returning
+// ctx.Err(). The context is checked between chunks and between blocks of
+// voxels, and a Read blocked on reader is abandoned.
+func DecodeContext(ctx context.Context, reader io.Reader, img Image) error {
+	r := bufio.NewReader(contextReader{ctx, reader})
+	if err := decode(ctx, r, img, DefaultDecodeOptions); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
+func decode(ctx context.Context, reader io.Reader, img Image, opts DecodeOptions) error {
 	var fileHeader voxHeader
 	if err := binary.Read(reader, binary.LittleEndian, &fileHeader); err != nil {
 		return ErrInvalidFile.with(err)
@@ -77,7 +242,7 @@ func Decode(reader io.Reader, img Image) error {
 		return ErrInvalidFile
 	}
 
-	if fileHeader.Version[0] != voxVersion {
+	if opts.StrictVersion && fileHeader.Version[0] != voxVersion {
 		return ErrInvalidVersion
 	}
 
@@ -91,16 +256,32 @@ func Decode(reader io.Reader, img Image) error {
 	}
 
 	var (
-		hasPalette bool
-		numBytes   uint32
+		hasPalette  bool
+		numBytes    uint32
+		totalVoxels uint64
+		palette     = indexedPalette(defaultPalette[:])
+		indexMap    []byte
+		voxels      []voxelData
+		models      []heldModel
 	)
 
 	childrenSize := header.ChildrenSize
+	progress, _ := img.(ProgressReporter)
+	visitor, _ := img.(chunkVisitor)
 	for numBytes < childrenSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
 			return ErrInvalidFile.with(err)
 		}
 		numBytes += 12
+		if numBytes > childrenSize {
+			return ErrInvalidChunk
+		}
+		if visitor != nil {
+			visitor.visitChunk(string(header.Id[:]))
+		}
 
 		switch string(header.Id[:]) {
 		case sizeShunkID:
@@ -110,20 +291,29 @@ func Decode(reader io.Reader, img Image) error {
 			}
 
 			numBytes += 12
-			img.SetBounds(voxel.Bx(0, 0, 0, int(size[0]), int(size[1]), int(size[2])))
+			b := voxel.Bx(0, 0, 0, int(size[0]), int(size[1]), int(size[2]))
+			if opts.ApplyIndexMap {
+				models = append(models, heldModel{b, len(voxels)})
+			} else {
+				img.SetBounds(b)
+			}
 		case paletteChunkID:
-			palette := make(color.Palette, 256)
-			for i := range palette {
-				var c color.RGBA
-				if err := binary.Read(reader, binary.LittleEndian, &c); err != nil {
-					return ErrInvalidChunk.with(err)
-				}
-				palette[i] = c
+			var chunk [256]color.RGBA
+			if err := binary.Read(reader, binary.LittleEndian, &chunk); err != nil {
+				return ErrInvalidChunk.with(err)
+			}
+
+			colors := make([]color.Color, len(chunk))
+			for i, c := range chunk {
+				colors[i] = c
 			}
+			palette = indexedPalette(colors)
 
 			hasPalette = true
-			numBytes += 16 * 256
-			img.SetPalette(palette)
+			numBytes += 4 * 256
+			if !opts.ApplyIndexMap {
+				img.SetPalette(palette)
+			}
 		case voxelChunkID:
 			var numVoxels uint32
 			if err := binary.Read(reader, binary.LittleEndian, &numVoxels); err != nil {
@@ -131,30 +321,276 @@ func Decode(reader io.Reader, img Image) error {
 			}
 			numBytes += 4
 
-			for i := uint32(0); i < numVoxels; i++ {
-				var voxel [4]byte
-				if err := binary.Read(reader, binary.LittleEndian, &voxel); err != nil {
+			totalVoxels += uint64(numVoxels)
+			if opts.MaxVoxels > 0 && totalVoxels > uint64(opts.MaxVoxels) {
+				return ErrTooManyVoxels
+			}
+
+			// Each voxel takes 4 bytes, so the count must fit in both the
+			// chunk and what is left of the main chunk.
+			remaining := uint64(childrenSize) - uint64(numBytes)
+			if uint64(numVoxels)*4 > remaining || uint64(numVoxels)*4+4 > uint64(header.DataSize) {
+				return ErrInvalidChunk
+			}
+
+			// Read the voxels in blocks rather than one by one. The size
+			// is only checked against the declared chunk sizes, so the
+			// block is capped to keep a lying file from forcing a huge
+			// allocation before the read fails.
+			size := numVoxels
+			if size > voxelBlock {
+				size = voxelBlock
+			}
+			buf := make([]byte, 4*size)
+			for left := numVoxels; left > 0; {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				n := left
+				if n > voxelBlock {
+					n = voxelBlock
+				}
+				block := buf[:4*n]
+				if _, err := io.ReadFull(reader, block); err != nil {
 					return ErrInvalidChunk.with(err)
 				}
-				img.Set(int(voxel[0]), int(voxel[1]), int(voxel[2]), voxel[3])
+
+				for i := 0; i < len(block); i += 4 {
+					v := block[i : i+4]
+					if opts.ApplyIndexMap {
+						voxels = append(voxels, voxelData{int(v[0]), int(v[1]), int(v[2]), v[3]})
+					} else {
+						img.Set(int(v[0]), int(v[1]), int(v[2]), v[3])
+					}
+				}
+				left -= n
+				if progress != nil && left > 0 {
+					progress.Progress(numBytes+4*(numVoxels-left), childrenSize)
+				}
 			}
 			numBytes += 4 * numVoxels
+		case indexMapID:
+			if header.DataSize != 256 || header.ChildrenSize != 0 {
+				return ErrInvalidChunk
+			}
+
+			indexMap = make([]byte, 256)
+			if _, err := io.ReadFull(reader, indexMap); err != nil {
+				return ErrInvalidChunk.with(err)
+			}
+			if opts.ApplyIndexMap && !isPermutation(indexMap) {
+				return ErrInvalidChunk
+			}
+			numBytes += 256
 		default:
-			sz := header.DataSize + header.ChildrenSize
-			if _, err := reader.Read(make([]byte, sz)); err != nil {
+			sz := uint64(header.DataSize) + uint64(header.ChildrenSize)
+			if sz > uint64(childrenSize)-uint64(numBytes) {
+				return ErrInvalidChunk
+			}
+			if _, err := io.CopyN(io.Discard, reader, int64(sz)); err != nil {
 				return ErrInvalidFile.with(err)
 			}
-			numBytes += sz
+			numBytes += uint32(sz)
+		}
+
+		if progress != nil {
+			progress.Progress(numBytes, childrenSize)
 		}
 	}
 
-	if !hasPalette {
-		img.SetPalette(defaultPalette[:])
+	if !opts.ApplyIndexMap {
+		if !hasPalette {
+			img.SetPalette(palette)
+		}
+		return nil
+	}
+
+	// The index map lists the RGBA chunk entries in display order. Voxel
+	// index i uses chunk entry i-1, so the last chunk entry is never used
+	// and is skipped wherever it is listed, while index 0 stays empty.
+	var mapping [256]uint8
+	for i := range mapping {
+		mapping[i] = uint8(i)
+	}
+	if indexMap != nil {
+		ordered := make(color.Palette, len(palette))
+		ordered[0] = palette[0]
+		slot := 1
+		for _, entry := range indexMap {
+			if entry == 255 {
+				continue
+			}
+			ordered[slot] = palette[entry+1]
+			mapping[entry+1] = uint8(slot)
+			slot++
+		}
+		palette = ordered
 	}
 
+	// Replay the models in file order, so every voxel is written within the
+	// bounds of its own model as it is by a plain decode.
+	set := func(voxels []voxelData) {
+		for _, v := range voxels {
+			img.Set(v.x, v.y, v.z, mapping[v.index])
+		}
+	}
+	var start int
+	for _, m := range models {
+		set(voxels[start:m.first])
+		img.SetBounds(m.bounds)
+		start = m.first
+	}
+	set(voxels[start:])
+	img.SetPalette(palette)
 	return nil
 }
 
+// DecodeBytes is like Decode but parses a file that is already in memory,
+// reading the fields straight from data instead of through an io.Reader.
+func DecodeBytes(data []byte, img Image) error {
+	le := binary.LittleEndian
+	if len(data) < 8 || string(data[:4]) != voxMagic {
+		return ErrInvalidFile
+	}
+	if data[4] != voxVersion {
+		return ErrInvalidVersion
+	}
+
+	data = data[8:]
+	if len(data) < 12 || string(data[:4]) != mainChunkID {
+		return ErrInvalidMainChunk
+	}
+
+	childrenSize := uint64(le.Uint32(data[8:]))
+	data = data[12:]
+	if uint64(len(data)) < childrenSize {
+		return ErrInvalidFile.with(io.ErrUnexpectedEOF)
+	}
+	data = data[:childrenSize]
+
+	hasPalette := false
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return ErrInvalidChunk
+		}
+		id, dataSize, childSize := string(data[:4]), uint64(le.Uint32(data[4:])), uint64(le.Uint32(data[8:]))
+		data = data[12:]
+
+		switch id {
+		case sizeShunkID:
+			if len(data) < 12 {
+				return ErrInvalidChunk.with(io.ErrUnexpectedEOF)
+			}
+			img.SetBounds(voxel.Bx(0, 0, 0, int(le.Uint32(data)), int(le.Uint32(data[4:])), int(le.Uint32(data[8:]))))
+			data = data[12:]
+		case paletteChunkID:
+			if len(data) < 4*256 {
+				return ErrInvalidChunk.with(io.ErrUnexpectedEOF)
+			}
+
+			colors := make([]color.Color, 256)
+			for i := range colors {
+				c := data[i*4 : i*4+4]
+				colors[i] = color.RGBA{c[0], c[1], c[2], c[3]}
+			}
+			img.SetPalette(indexedPalette(colors))
+			hasPalette = true
+			data = data[4*256:]
+		case voxelChunkID:
+			if len(data) < 4 {
+				return ErrInvalidChunk.with(io.ErrUnexpectedEOF)
+			}
+			numVoxels := uint64(le.Uint32(data))
+			data = data[4:]
+
+			if numVoxels*4 > uint64(len(data)) || numVoxels*4+4 > dataSize {
+				return ErrInvalidChunk
+			}
+			for i := uint64(0); i < numVoxels; i++ {
+				v := data[i*4 : i*4+4]
+				img.Set(int(v[0]), int(v[1]), int(v[2]), v[3])
+			}
+			data = data[numVoxels*4:]
+		case indexMapID:
+			if dataSize != 256 || childSize != 0 {
+				return ErrInvalidChunk
+			}
+			fallthrough
+		default:
+			sz := dataSize + childSize
+			if sz > uint64(len(data)) {
+				return ErrInvalidChunk
+			}
+			data = data[sz:]
+		}
+	}
+
+	if !hasPalette {
+		img.SetPalette(indexedPalette(defaultPalette[:]))
+	}
+	return nil
+}
+
+// DecodeFile decodes the .vox file at path with DecodeBytes. Where the
+// platform supports it the file is memory mapped rather than read, so large
+// files are not copied onto the heap. The mapping is released before
+// DecodeFile returns, so img must not keep references into the data.
+func DecodeFile(path string, img Image) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	info, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return ErrInvalidFile
+	}
+
+	data, unmap, err := mapFile(fp, int(info.Size()))
+	if err != nil {
+		return ErrInvalidFile.with(err)
+	}
+	defer unmap()
+	return DecodeBytes(data, img)
+}
+
+// isPermutation reports whether m holds each byte value exactly once.
+func isPermutation(m []byte) bool {
+	var seen [256]bool
+	for _, v := range m {
+		if seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	return len(m) == len(seen)
+}
+
+// indexedPalette maps the colors of an RGBA chunk to voxel indices.
+// MagicaVoxel indices are 1-based: a voxel with index i has the color stored
+// at position i-1 of the chunk, and index 0 is empty. The last color of the
+// chunk is unreachable and is dropped.
+func indexedPalette(colors []color.Color) color.Palette {
+	palette := make(color.Palette, 256)
+	palette[0] = color.RGBA{}
+	copy(palette[1:], colors)
+	return palette
+}
+
+// EmptyPalette is MagicaVoxel's default palette indexed the way voxels use
+// it: entry 0 is transparent and entry i is the editor's color i. Decode
+// uses these colors when the file has no RGBA chunk.
+var EmptyPalette = indexedPalette(defaultPalette[:])
+
+// DefaultPalette holds the same colors as EmptyPalette. Both are copies, so
+// changing them does not affect decoding.
+var DefaultPalette = indexedPalette(defaultPalette[:])
+
+// defaultPalette is the RGBA chunk MagicaVoxel assumes when a file has none.
 var defaultPalette = [256]color.Color{
 	color.RGBA{255, 255, 255, 255},
 	color.RGBA{255, 255, 204, 255},
@@ -5,11 +5,18 @@
 package vox
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"image"
 	"image/color"
 	"image/color/palette"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/andreas-jonsson/voxel/voxel"
 )
@@ -49,3 +56,528 @@ func TestVox(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestVoxColors(t *testing.T) {
+	fp, err := os.Open("test.vox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	img := voxel.NewPaletted(nil, voxel.ZB)
+	if err := Decode(fp, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// The model uses index 9 of the default palette, which MagicaVoxel
+	// shows as 0xff99ccff (ABGR).
+	expected := color.RGBA{255, 204, 153, 255}
+	var n int
+	img.EachVoxel(func(x, y, z int, index uint8) {
+		if index != 9 {
+			t.Fatalf("voxel %v has index %d, expected 9", voxel.Pt(x, y, z), index)
+		}
+		if c := img.Palette[index]; c != expected {
+			t.Fatalf("voxel has color %v, expected %v", c, expected)
+		}
+		n++
+	})
+	if n == 0 {
+		t.Error("no voxels decoded")
+	}
+
+	if c := img.Palette[0]; c != (color.RGBA{}) {
+		t.Errorf("index 0 has color %v, expected transparent", c)
+	}
+}
+
+func TestEmptyPalette(t *testing.T) {
+	if len(EmptyPalette) != 256 {
+		t.Fatalf("got %d entries, expected 256", len(EmptyPalette))
+	}
+	if _, _, _, a := EmptyPalette[0].RGBA(); a != 0 {
+		t.Errorf("entry 0 has alpha %d, expected 0", a)
+	}
+	for i := 1; i < len(EmptyPalette); i++ {
+		if EmptyPalette[i] != defaultPalette[i-1] {
+			t.Fatalf("entry %d is %v, expected %v", i, EmptyPalette[i], defaultPalette[i-1])
+		}
+	}
+	if c := EmptyPalette[1]; c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("entry 1 is %v, expected white", c)
+	}
+}
+
+func TestDefaultPalette(t *testing.T) {
+	if len(DefaultPalette) != 256 {
+		t.Fatalf("got %d entries, expected 256", len(DefaultPalette))
+	}
+
+	DefaultPalette[9] = color.RGBA{1, 2, 3, 255}
+	defer func() { DefaultPalette[9] = defaultPalette[8] }()
+
+	data := voxFile(150, sizeChunk(1, 1, 1), voxelChunk(1, [4]byte{0, 0, 0, 9}))
+	img := voxel.NewPaletted(nil, voxel.ZB)
+	if err := Decode(bytes.NewReader(data), img); err != nil {
+		t.Fatal(err)
+	}
+	if c := img.GetColor(0, 0, 0); c != defaultPalette[8] {
+		t.Errorf("decoded color %v, expected %v", c, defaultPalette[8])
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	fp, err := os.Open("test.vox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	var img voxel.Paletted
+	if err := DecodeInto(fp, &img); err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b != voxel.Bx(0, 0, 0, 21, 21, 21) {
+		t.Fatalf("got bounds %v, expected 21x21x21", b)
+	}
+
+	var n int
+	img.EachVoxel(func(x, y, z int, index uint8) { n++ })
+	if n != 2628 {
+		t.Errorf("got %d voxels, expected 2628", n)
+	}
+
+	data := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(2, [4]byte{1, 0, 1, 5}, [4]byte{7, 7, 7, 6}))
+	if err := DecodeInto(bytes.NewReader(data), &img); err != nil {
+		t.Fatal(err)
+	}
+	if idx := img.Get(1, 0, 1); idx != 5 {
+		t.Errorf("voxel has index %d, expected 5", idx)
+	}
+}
+
+func TestDecodeIntoReuse(t *testing.T) {
+	data := bigVoxFile()
+	var img voxel.Paletted
+	if err := DecodeInto(bytes.NewReader(data), &img); err != nil {
+		t.Fatal(err)
+	}
+	first := &img.Data[0]
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if err := DecodeInto(bytes.NewReader(data), &img); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if &img.Data[0] != first {
+		t.Error("second decode replaced the data")
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n >= uint64(len(img.Data)) {
+		t.Errorf("second decode allocated %d bytes for %d voxels", n, len(img.Data))
+	}
+
+	// A smaller model reuses the data and clears what it does not cover.
+	small := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{1, 1, 1, 3}))
+	if err := DecodeInto(bytes.NewReader(small), &img); err != nil {
+		t.Fatal(err)
+	}
+	if &img.Data[0] != first || len(img.Data) != 8 {
+		t.Fatalf("got %d bytes of data, expected the first 8 reused", len(img.Data))
+	}
+	if h := voxel.Histogram(&img); h[0] != 7 || h[3] != 1 {
+		t.Error("old voxels survived the second decode")
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	data, err := os.ReadFile("test.vox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := voxel.NewPaletted(nil, voxel.ZB)
+	if err := Decode(bytes.NewReader(data), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	img := voxel.NewPaletted(nil, voxel.ZB)
+	if err := DecodeBytes(data, img); err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != expected.Bounds() || !bytes.Equal(img.Data, expected.Data) {
+		t.Error("decoded data differs from Decode")
+	}
+	for i := range expected.Palette {
+		if img.Palette[i] != expected.Palette[i] {
+			t.Fatalf("palette entry %d is %v, expected %v", i, img.Palette[i], expected.Palette[i])
+		}
+	}
+
+	data = voxFile(150, sizeChunk(2, 2, 2), voxelChunk(0xfffffff0, [4]byte{0, 0, 0, 1}))
+	if err := DecodeBytes(data, img); err != ErrInvalidChunk {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidChunk)
+	}
+	data = voxFile(150, sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{0, 0, 0, 1}))
+	if err := DecodeBytes(data[:len(data)-1], img); err == nil {
+		t.Error("expected error on truncated file")
+	}
+}
+
+func TestDecodeFunc(t *testing.T) {
+	data, err := os.ReadFile("test.vox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := voxel.NewPaletted(nil, voxel.ZB)
+	if err := Decode(bytes.NewReader(data), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		size    voxel.Box
+		pal     color.Palette
+		decoded []voxelData
+	)
+	err = DecodeFunc(bytes.NewReader(data),
+		func(b voxel.Box) { size = b },
+		func(p color.Palette) { pal = p },
+		func(x, y, z int, index uint8) { decoded = append(decoded, voxelData{x, y, z, index}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != expected.Bounds() || len(pal) != len(expected.Palette) {
+		t.Fatalf("got size %v and %d colors", size, len(pal))
+	}
+
+	var n int
+	expected.EachVoxel(func(x, y, z int, index uint8) { n++ })
+	if len(decoded) != n {
+		t.Fatalf("got %d voxels, expected %d", len(decoded), n)
+	}
+	for _, v := range decoded {
+		if idx := expected.Get(v.x, v.y, v.z); idx != v.index {
+			t.Fatalf("voxel %v has index %d, expected %d", voxel.Pt(v.x, v.y, v.z), v.index, idx)
+		}
+	}
+
+	if err := DecodeFunc(bytes.NewReader(data), nil, nil, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.vox")
+	data := bigVoxFile()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	count := funcImage{onVoxel: func(x, y, z int, index uint8) { n++ }}
+	allocated := func(fn func() error) uint64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		if err := fn(); err != nil {
+			t.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+
+	mapped := allocated(func() error { return DecodeFile(path, count) })
+	if n != 128*128*64 {
+		t.Errorf("got %d voxels, expected %d", n, 128*128*64)
+	}
+	read := allocated(func() error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return DecodeBytes(data, count)
+	})
+
+	t.Logf("DecodeFile allocated %d bytes, reading the file %d", mapped, read)
+	if mapped >= uint64(len(data)) || mapped >= read {
+		t.Errorf("DecodeFile allocated %d bytes for a %d byte file", mapped, len(data))
+	}
+}
+
+type progressImage struct {
+	voxelImage
+	calls       int
+	done, total uint32
+}
+
+func (img *progressImage) Progress(done, total uint32) {
+	if done < img.done {
+		panic("progress went backwards")
+	}
+	img.calls++
+	img.done, img.total = done, total
+}
+
+func TestDecodeProgress(t *testing.T) {
+	var img progressImage
+	if err := Decode(bytes.NewReader(bigVoxFile()), &img); err != nil {
+		t.Fatal(err)
+	}
+	if img.calls < 3 || img.done != img.total || img.total == 0 {
+		t.Errorf("got %d calls, last reporting %d of %d", img.calls, img.done, img.total)
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	data := bigVoxFile()
+	img := voxel.NewPaletted(nil, voxel.ZB)
+	if err := DecodeContext(context.Background(), bytes.NewReader(data), img); err != nil {
+		t.Fatal(err)
+	}
+	if idx := img.Get(127, 127, 63); idx != 1+127 {
+		t.Fatalf("last voxel is %d, expected %d", idx, 1+127)
+	}
+
+	// Send half the file and then block, as a stalled network reader would.
+	r, w := io.Pipe()
+	defer w.Close()
+	go w.Write(data[:len(data)/2])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- DecodeContext(ctx, r, &voxelImage{})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("got %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("decode did not return after cancel")
+	}
+}
+
+// bigVoxFile returns a file with 128x128x64 voxels, all set.
+func bigVoxFile() []byte {
+	const w, h, d = 128, 128, 64
+	voxels := make([][4]byte, 0, w*h*d)
+	for z := 0; z < d; z++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				voxels = append(voxels, [4]byte{uint8(x), uint8(y), uint8(z), uint8(1 + x%255)})
+			}
+		}
+	}
+	return voxFile(150, sizeChunk(w, h, d), voxelChunk(uint32(len(voxels)), voxels...))
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := bigVoxFile()
+	img := voxel.NewPaletted(nil, voxel.ZB)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Decode(bytes.NewReader(data), img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBytes(b *testing.B) {
+	data := bigVoxFile()
+	img := voxel.NewPaletted(nil, voxel.ZB)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DecodeBytes(data, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func voxChunk(id string, data []byte, children ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+
+	var childData []byte
+	for _, c := range children {
+		childData = append(childData, c...)
+	}
+
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(data)), uint32(len(childData))})
+	buf.Write(data)
+	buf.Write(childData)
+	return buf.Bytes()
+}
+
+func voxFile(version uint32, chunks ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(voxMagic)
+	binary.Write(&buf, binary.LittleEndian, version)
+	buf.Write(voxChunk(mainChunkID, nil, chunks...))
+	return buf.Bytes()
+}
+
+func sizeChunk(x, y, z uint32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint32{x, y, z})
+	return voxChunk(sizeShunkID, buf.Bytes())
+}
+
+func voxelChunk(count uint32, voxels ...[4]byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, count)
+	for _, v := range voxels {
+		buf.Write(v[:])
+	}
+	return voxChunk(voxelChunkID, buf.Bytes())
+}
+
+func TestDecodeOptions(t *testing.T) {
+	data := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(1000000000, [4]byte{0, 0, 0, 1}))
+
+	var img voxelImage
+	err := DecodeWithOptions(bytes.NewReader(data), &img, DecodeOptions{MaxVoxels: 1 << 20})
+	if err != ErrTooManyVoxels {
+		t.Errorf("got error %v, expected %v", err, ErrTooManyVoxels)
+	}
+
+	data = voxFile(200, sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{1, 0, 1, 5}))
+	if err := Decode(bytes.NewReader(data), &img); err != ErrInvalidVersion {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidVersion)
+	}
+	if err := DecodeWithOptions(bytes.NewReader(data), &img, DecodeOptions{}); err != nil {
+		t.Error(err)
+	}
+	if idx := img.data[1].ColorIndexAt(1, 0); idx != 5 {
+		t.Errorf("voxel has index %d, expected 5", idx)
+	}
+}
+
+func TestDecodeIndexMap(t *testing.T) {
+	imap := make([]byte, 256)
+	for i := range imap {
+		imap[i] = uint8(255 - i)
+	}
+
+	data := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{1, 1, 1, 5}), voxChunk(indexMapID, imap))
+
+	var img voxelImage
+	if err := DecodeWithOptions(bytes.NewReader(data), &img, DecodeOptions{ApplyIndexMap: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	layer := img.data[1]
+	// Chunk entry 255 is unused, so entry 4 is listed in display slot 251.
+	if idx := layer.ColorIndexAt(1, 1); idx != 251 {
+		t.Errorf("voxel has index %d, expected 251", idx)
+	}
+	if c := layer.At(1, 1); c != defaultPalette[4] {
+		t.Errorf("voxel has color %v, expected %v", c, defaultPalette[4])
+	}
+}
+
+func TestDecodeIndexMapModels(t *testing.T) {
+	imap := make([]byte, 256)
+	for i := range imap {
+		imap[i] = uint8(i)
+	}
+	imap[0], imap[2] = 2, 0
+
+	// The first model is larger than the last, which is the one kept.
+	data := voxFile(150,
+		sizeChunk(10, 10, 10), voxelChunk(1, [4]byte{9, 9, 9, 1}),
+		sizeChunk(2, 2, 2), voxelChunk(1, [4]byte{1, 1, 1, 3}),
+		voxChunk(indexMapID, imap),
+	)
+
+	var models []voxel.Box
+	var voxels []voxel.Point
+	onVoxel := func(x, y, z int, index uint8) {
+		if b := models[len(models)-1]; !voxel.Pt(x, y, z).In(b) {
+			t.Errorf("voxel %v written outside %v", voxel.Pt(x, y, z), b)
+		}
+		// The IMAP swaps the colors of indices 1 and 3.
+		if expected := [2]uint8{3, 1}[len(models)-1]; index != expected {
+			t.Errorf("voxel %v has index %d, expected %d", voxel.Pt(x, y, z), index, expected)
+		}
+		voxels = append(voxels, voxel.Pt(x, y, z))
+	}
+	img := funcImage{onSize: func(b voxel.Box) { models = append(models, b) }, onVoxel: onVoxel}
+	if err := DecodeWithOptions(bytes.NewReader(data), img, DecodeOptions{ApplyIndexMap: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || len(voxels) != 2 {
+		t.Errorf("got %d models and %d voxels, expected 2 of each", len(models), len(voxels))
+	}
+
+	p := voxel.NewPaletted(nil, voxel.ZB)
+	if err := DecodeWithOptions(bytes.NewReader(data), palettedImage{p}, DecodeOptions{ApplyIndexMap: true}); err != nil {
+		t.Fatal(err)
+	}
+	if idx := p.Get(1, 1, 1); idx != 1 || p.Bounds() != voxel.Bx(0, 0, 0, 2, 2, 2) {
+		t.Errorf("got index %d in %v", idx, p.Bounds())
+	}
+}
+
+func TestDecodeIndexMapFirstSlot(t *testing.T) {
+	// Swap the first chunk entry, used by index 1, with entry 4, used by
+	// index 5.
+	imap := make([]byte, 256)
+	for i := range imap {
+		imap[i] = uint8(i)
+	}
+	imap[0], imap[4] = 4, 0
+
+	data := voxFile(150, sizeChunk(2, 2, 1), voxelChunk(2, [4]byte{0, 0, 0, 5}, [4]byte{1, 0, 0, 1}), voxChunk(indexMapID, imap))
+	img := voxel.NewPaletted(nil, voxel.ZB)
+	if err := DecodeWithOptions(bytes.NewReader(data), palettedImage{img}, DecodeOptions{ApplyIndexMap: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if idx := img.Get(0, 0, 0); idx != 1 {
+		t.Errorf("voxel moved into the first slot has index %d, expected 1", idx)
+	}
+	if idx := img.Get(1, 0, 0); idx != 5 {
+		t.Errorf("voxel moved out of the first slot has index %d, expected 5", idx)
+	}
+	if c := img.GetColor(0, 0, 0); c != defaultPalette[4] {
+		t.Errorf("got color %v, expected %v", c, defaultPalette[4])
+	}
+	if c := img.GetColor(1, 0, 0); c != defaultPalette[0] {
+		t.Errorf("got color %v, expected %v", c, defaultPalette[0])
+	}
+	if c := img.Palette[0]; c != (color.RGBA{}) {
+		t.Errorf("empty index has color %v, expected transparent", c)
+	}
+
+	imap[4] = 4
+	data = voxFile(150, sizeChunk(2, 2, 1), voxChunk(indexMapID, imap))
+	if err := DecodeWithOptions(bytes.NewReader(data), palettedImage{img}, DecodeOptions{ApplyIndexMap: true}); err != ErrInvalidChunk {
+		t.Errorf("got %v for an IMAP that is not a permutation, expected %v", err, ErrInvalidChunk)
+	}
+}
+
+func TestDecodeVoxelCountGuard(t *testing.T) {
+	// The count claims far more voxels than the chunk holds.
+	data := voxFile(150, sizeChunk(2, 2, 2), voxelChunk(0xfffffff0, [4]byte{0, 0, 0, 1}))
+
+	var img voxelImage
+	if err := Decode(bytes.NewReader(data), &img); err != ErrInvalidChunk {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidChunk)
+	}
+
+	// The body is cut short of the declared voxels.
+	data = voxFile(150, sizeChunk(2, 2, 2), voxelChunk(2, [4]byte{0, 0, 0, 1}, [4]byte{1, 1, 1, 2}))
+	if err := Decode(bytes.NewReader(data[:len(data)-2]), &img); err == nil {
+		t.Error("expected error on truncated body")
+	}
+}